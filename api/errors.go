@@ -0,0 +1,56 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"rider-assignment-system/dispatch"
+	"rider-assignment-system/geofence"
+	"rider-assignment-system/matching"
+	"rider-assignment-system/pricing"
+)
+
+// statusError is returned from inside a transaction to pick the HTTP response once it has rolled back.
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+// writeTxError responds with the status carried by err, or a 500 with fallback for unexpected failures.
+func writeTxError(w http.ResponseWriter, err error, fallback string) {
+	if se, ok := err.(*statusError); ok {
+		http.Error(w, se.message, se.status)
+		return
+	}
+	log.Printf("%s: %v", fallback, err)
+	http.Error(w, fallback, http.StatusInternalServerError)
+}
+
+// writeDispatchError maps dispatch, matching and pricing errors onto HTTP statuses.
+func writeDispatchError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case matching.ErrNoDriverAvailable:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case dispatch.ErrTripNotFound:
+		http.Error(w, "Trip not found", http.StatusNotFound)
+	case dispatch.ErrOfferNotFound:
+		http.Error(w, "Offer not found", http.StatusNotFound)
+	case dispatch.ErrOfferNotPending:
+		http.Error(w, "Offer is no longer pending", http.StatusConflict)
+	case geofence.ErrOutsideServiceArea:
+		http.Error(w, "Pickup and dropoff must be inside the service area", http.StatusUnprocessableEntity)
+	case geofence.ErrPickupNotAllowed:
+		http.Error(w, "Pickups are not allowed at this location", http.StatusUnprocessableEntity)
+	case pricing.ErrNoPricingPlan, pricing.ErrEstimateMismatch:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case pricing.ErrEstimateNotFound:
+		http.Error(w, "Fare estimate not found", http.StatusNotFound)
+	case pricing.ErrEstimateExpired, pricing.ErrEstimateUsed:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		writeTxError(w, err, fallback)
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"rider-assignment-system/geofence"
+	"rider-assignment-system/models"
+	"rider-assignment-system/pricing"
+)
+
+// EstimateFare handles quoting the fare of a ride before it is requested. The estimate's ID can
+// be passed to POST /trips as fare_estimate_id until it expires to lock the quoted price.
+func EstimateFare(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		RiderID      int64   `json:"rider_id"` // Optional: restricts the estimate to this rider's trips
+		StartLat     float64 `json:"start_latitude"`
+		StartLon     float64 `json:"start_longitude"`
+		EndLat       float64 `json:"end_latitude"`
+		EndLon       float64 `json:"end_longitude"`
+		VehicleClass string  `json:"vehicle_class"` // Optional: defaults to "standard"
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	estimate, err := pricing.Estimate(r.Context(), pricing.EstimateRequest{
+		RiderID:      request.RiderID,
+		StartLat:     request.StartLat,
+		StartLon:     request.StartLon,
+		EndLat:       request.EndLat,
+		EndLon:       request.EndLon,
+		VehicleClass: request.VehicleClass,
+	})
+	if err != nil {
+		writeDispatchError(w, err, "Failed to estimate fare")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(estimate)
+}
+
+// CreatePricingPlan handles adding the rates for a vehicle class in a zone, or everywhere else
+// when no zone_id is given
+func CreatePricingPlan(w http.ResponseWriter, r *http.Request) {
+	var plan models.PricingPlan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := pricing.ValidatePlan(&plan); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := pricing.CreatePlan(r.Context(), plan)
+	switch err {
+	case nil:
+	case pricing.ErrPlanExists:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case geofence.ErrZoneNotFound:
+		http.Error(w, "Zone not found", http.StatusBadRequest)
+		return
+	default:
+		log.Printf("Failed to create pricing plan: %v", err)
+		http.Error(w, "Failed to create pricing plan", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetPricingPlans handles listing every pricing plan
+func GetPricingPlans(w http.ResponseWriter, r *http.Request) {
+	plans, err := pricing.ListPlans(r.Context())
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plans)
+}
@@ -1,18 +1,18 @@
 package api
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"math"
 	"net/http"
 	"rider-assignment-system/cache"
 	"rider-assignment-system/database"
+	"rider-assignment-system/dispatch"
+	"rider-assignment-system/geofence"
 	"rider-assignment-system/geohash"
 	"rider-assignment-system/matching"
 	"rider-assignment-system/models"
+	"rider-assignment-system/routing"
 	"strconv"
 	"strings"
 
@@ -28,6 +28,10 @@ func RequestRide(w http.ResponseWriter, r *http.Request) {
 		StartLon float64 `json:"start_longitude"`
 		EndLat   float64 `json:"end_latitude"`
 		EndLon   float64 `json:"end_longitude"`
+		Strategy string  `json:"strategy"` // Optional: overrides the configured matching strategy
+
+		// Optional: upfront fare estimate (from POST /fares/estimate) whose price the trip locks
+		FareEstimateID *int64 `json:"fare_estimate_id"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&tripRequest)
@@ -35,44 +39,54 @@ func RequestRide(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
-
-	// Find the nearest available driver
-	driver, err := matching.FindNearestDriver(tripRequest.StartLat, tripRequest.StartLon)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	if _, err := matching.GetMatcher(tripRequest.Strategy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Create a new trip
-	var tripID int64
-	err = database.DB.QueryRow(
-		`INSERT INTO trips (rider_id, driver_id, start_latitude, start_longitude, end_latitude, end_longitude, status)
-         VALUES ($1, $2, $3, $4, $5, $6, 'requested') RETURNING id`,
-		tripRequest.RiderID, driver.ID, tripRequest.StartLat, tripRequest.StartLon, tripRequest.EndLat, tripRequest.EndLon,
-	).Scan(&tripID)
-	if err != nil {
-		http.Error(w, "Failed to create trip", http.StatusInternalServerError)
-		return
-	}
+	// Create the trip and offer it to the nearest available driver
+	assignment, err := dispatch.RequestTrip(r.Context(), dispatch.TripRequest{
+		RiderID:  tripRequest.RiderID,
+		StartLat: tripRequest.StartLat,
+		StartLon: tripRequest.StartLon,
+		EndLat:   tripRequest.EndLat,
+		EndLon:   tripRequest.EndLon,
+		Strategy: tripRequest.Strategy,
 
-	// Update driver's status to 'on_trip' in the database
-	_, err = database.DB.Exec(`UPDATE drivers SET status='on_trip' WHERE id=$1`, driver.ID)
+		FareEstimateID: tripRequest.FareEstimateID,
+	})
 	if err != nil {
-		http.Error(w, "Failed to update driver status", http.StatusInternalServerError)
+		writeDispatchError(w, err, "Failed to create trip")
 		return
 	}
 
-	// Remove driver from Redis cache
-	ctx := context.Background()
-	driverHash := driver.Geohash
-	driverJSON, _ := json.Marshal(driver)
-	cache.Rdb.SRem(ctx, fmt.Sprintf("drivers:%s", driverHash), driverJSON)
-
-	// Respond to the rider with driver details
+	// Respond to the rider with the offered driver and the distance that decided the match
+	match := assignment.Candidate
+	driver := match.Driver
+	driver.Status = models.DriverReserved
 	response := map[string]interface{}{
-		"message": "Driver assigned",
-		"trip_id": tripID,
-		"driver":  driver,
+		"message":          "Driver offered",
+		"trip_id":          assignment.TripID,
+		"status":           models.TripDriverAssigned,
+		"driver":           driver,
+		"offer_id":         assignment.Offer.ID,
+		"offer_expires_at": assignment.Offer.ExpiresAt,
+		"distance_km":      match.DistanceKm,
+		"distance_source":  match.DistanceSource,
+		"search_radius_km": match.SearchRadiusKm,
+	}
+	if match.ETASeconds > 0 {
+		response["eta_seconds"] = match.ETASeconds
+	}
+	if match.QueuePosition > 0 {
+		response["queue_position"] = match.QueuePosition
+	}
+	if assignment.FareEstimateID != nil {
+		response["fare_estimate_id"] = *assignment.FareEstimateID
+		response["fare"] = assignment.Fare
+	}
+	if assignment.SurgeMultiplier != nil {
+		response["surge_multiplier"] = *assignment.SurgeMultiplier
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -84,7 +98,7 @@ func UpdateDriverLocation(w http.ResponseWriter, r *http.Request) {
 		DriverID  int64   `json:"driver_id"`
 		Latitude  float64 `json:"latitude"`
 		Longitude float64 `json:"longitude"`
-		Status    string  `json:"status"` // Optional: "available" or "on_trip"
+		Status    string  `json:"status"` // Optional: "available" or "offline"
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&locationUpdate)
@@ -116,32 +130,45 @@ func UpdateDriverLocation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Calculate new geohash
-	newGeohash := geohash.Encode(locationUpdate.Latitude, locationUpdate.Longitude, 5)
+	newGeohash := geohash.EncodeAt(locationUpdate.Latitude, locationUpdate.Longitude)
 
-	// Update driver's location and status in the database
+	// Update driver's location, status and heartbeat in the database
 	status := locationUpdate.Status
 	if status == "" {
 		status = currentDriver.Status
 	}
-	_, err = database.DB.Exec(
-		`UPDATE drivers SET latitude=$1, longitude=$2, geohash=$3, status=$4 WHERE id=$5`,
+	if !models.IsDriverStatus(status) {
+		http.Error(w, fmt.Sprintf("Unknown driver status %q", status), http.StatusBadRequest)
+		return
+	}
+	if !models.CanDriverSetStatus(currentDriver.Status, status) {
+		http.Error(w, fmt.Sprintf("Cannot change driver status from %s to %s", currentDriver.Status, status), http.StatusConflict)
+		return
+	}
+	if status == models.DriverAvailable {
+		if err := geofence.CheckServiceArea(locationUpdate.Latitude, locationUpdate.Longitude); err != nil {
+			http.Error(w, "Drivers can only be available inside the service area", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+	updated, err := updateDriverStatus(
+		`UPDATE drivers SET latitude=$1, longitude=$2, geohash=$3, status=$4, last_seen_at=now()
+         WHERE id=$5 AND (status=$4 OR status NOT IN ($6, $7))`,
 		locationUpdate.Latitude, locationUpdate.Longitude, newGeohash, status, locationUpdate.DriverID,
+		models.DriverReserved, models.DriverOnTrip,
 	)
 	if err != nil {
 		http.Error(w, "Failed to update driver", http.StatusInternalServerError)
 		return
 	}
-
-	ctx := context.Background()
-
-	// Remove driver from old geohash set in Redis
-	if currentDriver.Geohash != "" {
-		currentDriverJSON, _ := json.Marshal(currentDriver)
-		cache.Rdb.SRem(ctx, fmt.Sprintf("drivers:%s", currentDriver.Geohash), currentDriverJSON)
+	if !updated {
+		http.Error(w, "Driver was reserved for a trip in the meantime", http.StatusConflict)
+		return
 	}
 
-	// Add driver to new geohash set in Redis if status is 'available'
-	if status == "available" {
+	// Move the driver in the availability index, or take them out of it if no longer available
+	ctx := r.Context()
+	if status == models.DriverAvailable {
 		updatedDriver := models.Driver{
 			ID:        locationUpdate.DriverID,
 			Name:      currentDriver.Name,
@@ -150,8 +177,13 @@ func UpdateDriverLocation(w http.ResponseWriter, r *http.Request) {
 			Geohash:   newGeohash,
 			Status:    status,
 		}
-		updatedDriverJSON, _ := json.Marshal(updatedDriver)
-		cache.Rdb.SAdd(ctx, fmt.Sprintf("drivers:%s", newGeohash), updatedDriverJSON)
+		cache.ApplyUpdate("move available driver", func() error {
+			return cache.AddAvailableDriver(ctx, updatedDriver)
+		})
+	} else {
+		cache.ApplyUpdate("remove unavailable driver", func() error {
+			return cache.RemoveAvailableDriver(ctx, locationUpdate.DriverID)
+		})
 	}
 
 	// Respond with success message
@@ -160,11 +192,12 @@ func UpdateDriverLocation(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// DriverStatusUpdate allows drivers to update their status (e.g., from 'on_trip' to 'available')
+// DriverStatusUpdate allows drivers to go available or offline. Drivers who are reserved or on a
+// trip are released by the offer and trip endpoints instead.
 func DriverStatusUpdate(w http.ResponseWriter, r *http.Request) {
 	var statusUpdate struct {
 		DriverID int64  `json:"driver_id"`
-		Status   string `json:"status"` // "available", "on_trip"
+		Status   string `json:"status"` // "available" or "offline"
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&statusUpdate)
@@ -172,21 +205,14 @@ func DriverStatusUpdate(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
-
-	// Update driver's status in the database
-	_, err = database.DB.Exec(
-		`UPDATE drivers SET status=$1 WHERE id=$2`,
-		statusUpdate.Status, statusUpdate.DriverID,
-	)
-	if err != nil {
-		http.Error(w, "Failed to update driver status", http.StatusInternalServerError)
+	if !models.IsDriverStatus(statusUpdate.Status) {
+		http.Error(w, fmt.Sprintf("Unknown driver status %q", statusUpdate.Status), http.StatusBadRequest)
 		return
 	}
 
-	// Update Redis cache accordingly
 	var driver models.Driver
 	err = database.DB.QueryRow(
-		`SELECT id, name, latitude, longitude, geohash FROM drivers WHERE id=$1`,
+		`SELECT id, name, latitude, longitude, geohash, status FROM drivers WHERE id=$1`,
 		statusUpdate.DriverID,
 	).Scan(
 		&driver.ID,
@@ -194,20 +220,52 @@ func DriverStatusUpdate(w http.ResponseWriter, r *http.Request) {
 		&driver.Latitude,
 		&driver.Longitude,
 		&driver.Geohash,
+		&driver.Status,
 	)
 	if err != nil {
-		http.Error(w, "Failed to retrieve driver data", http.StatusInternalServerError)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Driver not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to retrieve driver data", http.StatusInternalServerError)
+		}
 		return
 	}
+	if !models.CanDriverSetStatus(driver.Status, statusUpdate.Status) {
+		http.Error(w, fmt.Sprintf("Cannot change driver status from %s to %s", driver.Status, statusUpdate.Status), http.StatusConflict)
+		return
+	}
+	if statusUpdate.Status == models.DriverAvailable && driver.Geohash != "" {
+		if err := geofence.CheckServiceArea(driver.Latitude, driver.Longitude); err != nil {
+			http.Error(w, "Drivers can only be available inside the service area", http.StatusUnprocessableEntity)
+			return
+		}
+	}
 
-	ctx := context.Background()
-	driverJSON, _ := json.Marshal(driver)
-	driverKey := fmt.Sprintf("drivers:%s", driver.Geohash)
+	// Update driver's status in the database, unless a trip reserved them in the meantime
+	updated, err := updateDriverStatus(
+		`UPDATE drivers SET status=$1 WHERE id=$2 AND (status=$1 OR status NOT IN ($3, $4))`,
+		statusUpdate.Status, statusUpdate.DriverID, models.DriverReserved, models.DriverOnTrip,
+	)
+	if err != nil {
+		http.Error(w, "Failed to update driver status", http.StatusInternalServerError)
+		return
+	}
+	if !updated {
+		http.Error(w, "Driver was reserved for a trip in the meantime", http.StatusConflict)
+		return
+	}
+	driver.Status = statusUpdate.Status
 
-	if statusUpdate.Status == "available" {
-		cache.Rdb.SAdd(ctx, driverKey, driverJSON)
+	// Update Redis cache accordingly
+	ctx := r.Context()
+	if driver.Status == models.DriverAvailable {
+		cache.ApplyUpdate("add available driver", func() error {
+			return cache.AddAvailableDriver(ctx, driver)
+		})
 	} else {
-		cache.Rdb.SRem(ctx, driverKey, driverJSON)
+		cache.ApplyUpdate("remove unavailable driver", func() error {
+			return cache.RemoveAvailableDriver(ctx, driver.ID)
+		})
 	}
 
 	// Respond with success message
@@ -216,6 +274,16 @@ func DriverStatusUpdate(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// updateDriverStatus runs a conditional update of a driver's row and reports whether it matched.
+func updateDriverStatus(query string, args ...interface{}) (bool, error) {
+	result, err := database.DB.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
 // GetDriver handles fetching driver details by ID
 func GetDriver(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -228,7 +296,7 @@ func GetDriver(w http.ResponseWriter, r *http.Request) {
 
 	var driver models.Driver
 	err = database.DB.QueryRow(
-		`SELECT id, name, latitude, longitude, geohash, status FROM drivers WHERE id=$1`,
+		`SELECT id, name, latitude, longitude, geohash, status, last_seen_at FROM drivers WHERE id=$1`,
 		driverID,
 	).Scan(
 		&driver.ID,
@@ -237,6 +305,7 @@ func GetDriver(w http.ResponseWriter, r *http.Request) {
 		&driver.Longitude,
 		&driver.Geohash,
 		&driver.Status,
+		&driver.LastSeenAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -262,19 +331,39 @@ func GetTrip(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var trip models.Trip
+	var driverID sql.NullInt64
 	err = database.DB.QueryRow(
-		`SELECT id, rider_id, driver_id, start_latitude, start_longitude, end_latitude, end_longitude, status FROM trips WHERE id=$1`,
+		`SELECT id, rider_id, driver_id, start_latitude, start_longitude, end_latitude, end_longitude, status,
+                eta_seconds, fare_estimate_id, fare, surge_multiplier, pickup_zone_id, dropoff_zone_id,
+                requested_at, driver_assigned_at, accepted_at, driver_arrived_at, in_progress_at,
+                completed_at, cancelled_at, expired_at
+         FROM trips WHERE id=$1`,
 		tripID,
 	).Scan(
 		&trip.ID,
 		&trip.RiderID,
-		&trip.DriverID,
+		&driverID,
 		&trip.StartLat,
 		&trip.StartLon,
 		&trip.EndLat,
 		&trip.EndLon,
 		&trip.Status,
+		&trip.ETASeconds,
+		&trip.FareEstimateID,
+		&trip.Fare,
+		&trip.SurgeMultiplier,
+		&trip.PickupZoneID,
+		&trip.DropoffZoneID,
+		&trip.RequestedAt,
+		&trip.DriverAssignedAt,
+		&trip.AcceptedAt,
+		&trip.DriverArrivedAt,
+		&trip.InProgressAt,
+		&trip.CompletedAt,
+		&trip.CancelledAt,
+		&trip.ExpiredAt,
 	)
+	trip.DriverID = driverID.Int64
 	if err != nil {
 		if err == sql.ErrNoRows {
 			http.Error(w, "Trip not found", http.StatusNotFound)
@@ -299,19 +388,30 @@ func CreateDriver(w http.ResponseWriter, r *http.Request) {
 
 	// Calculate geohash if latitude and longitude are provided
 	if driver.Latitude != 0 && driver.Longitude != 0 {
-		driver.Geohash = geohash.Encode(driver.Latitude, driver.Longitude, 5)
+		driver.Geohash = geohash.EncodeAt(driver.Latitude, driver.Longitude)
 	}
 
 	// Set default status if not provided
 	if driver.Status == "" {
-		driver.Status = "available"
+		driver.Status = models.DriverAvailable
+	}
+	if driver.Status != models.DriverAvailable && driver.Status != models.DriverOffline {
+		http.Error(w, "New drivers must be available or offline", http.StatusBadRequest)
+		return
+	}
+	if driver.Status == models.DriverAvailable && driver.Geohash != "" {
+		if err := geofence.CheckServiceArea(driver.Latitude, driver.Longitude); err != nil {
+			http.Error(w, "Drivers can only be available inside the service area", http.StatusUnprocessableEntity)
+			return
+		}
 	}
 
 	// Insert new driver into the database
 	err = database.DB.QueryRow(
-		`INSERT INTO drivers (name, latitude, longitude, geohash, status) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		`INSERT INTO drivers (name, latitude, longitude, geohash, status, last_seen_at)
+         VALUES ($1, $2, $3, $4, $5, now()) RETURNING id, last_seen_at`,
 		driver.Name, driver.Latitude, driver.Longitude, driver.Geohash, driver.Status,
-	).Scan(&driver.ID)
+	).Scan(&driver.ID, &driver.LastSeenAt)
 	if err != nil {
 		if pgErr, ok := err.(*pq.Error); ok && strings.Contains(pgErr.Message, "duplicate key") {
 			http.Error(w, "Driver already exists", http.StatusConflict)
@@ -321,11 +421,12 @@ func CreateDriver(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Add driver to Redis cache if status is 'available' and geohash is set
-	if driver.Status == "available" && driver.Geohash != "" {
-		ctx := context.Background()
-		driverJSON, _ := json.Marshal(driver)
-		cache.Rdb.SAdd(ctx, fmt.Sprintf("drivers:%s", driver.Geohash), driverJSON)
+	// Add driver to the availability index if status is 'available' and a location is set
+	if driver.Status == models.DriverAvailable && driver.Geohash != "" {
+		ctx := r.Context()
+		cache.ApplyUpdate("add available driver", func() error {
+			return cache.AddAvailableDriver(ctx, driver)
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -355,135 +456,6 @@ func CreateRider(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(rider)
 }
 
-// CompleteTrip handles marking a trip as completed
-func CompleteTrip(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	tripIDStr := vars["trip_id"]
-	tripID, err := strconv.ParseInt(tripIDStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid trip ID", http.StatusBadRequest)
-		return
-	}
-
-	// Update trip status to 'completed'
-	_, err = database.DB.Exec(
-		`UPDATE trips SET status='completed' WHERE id=$1`,
-		tripID,
-	)
-	if err != nil {
-		http.Error(w, "Failed to update trip", http.StatusInternalServerError)
-		return
-	}
-
-	// Get the driver associated with the trip
-	var driverID int64
-	err = database.DB.QueryRow(
-		`SELECT driver_id FROM trips WHERE id=$1`,
-		tripID,
-	).Scan(&driverID)
-	if err != nil {
-		http.Error(w, "Failed to retrieve trip details", http.StatusInternalServerError)
-		return
-	}
-
-	// Update driver's status to 'available' in the database
-	_, err = database.DB.Exec(
-		`UPDATE drivers SET status='available' WHERE id=$1`,
-		driverID,
-	)
-	if err != nil {
-		http.Error(w, "Failed to update driver status", http.StatusInternalServerError)
-		return
-	}
-
-	// Add driver back to Redis cache
-	var driver models.Driver
-	err = database.DB.QueryRow(
-		`SELECT id, name, latitude, longitude, geohash, status FROM drivers WHERE id=$1`,
-		driverID,
-	).Scan(
-		&driver.ID,
-		&driver.Name,
-		&driver.Latitude,
-		&driver.Longitude,
-		&driver.Geohash,
-		&driver.Status,
-	)
-	if err != nil {
-		http.Error(w, "Failed to retrieve driver data", http.StatusInternalServerError)
-		return
-	}
-
-	if driver.Status == "available" && driver.Geohash != "" {
-		ctx := context.Background()
-		driverJSON, _ := json.Marshal(driver)
-		cache.Rdb.SAdd(ctx, fmt.Sprintf("drivers:%s", driver.Geohash), driverJSON)
-	}
-
-	response := map[string]string{"message": "Trip completed"}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-// Haversine function to calculate the distance between two latitude/longitude points
-func haversine(lat1, lon1, lat2, lon2 float64) float64 {
-	const R = 6371 // Earth's radius in km
-
-	dLat := (lat2 - lat1) * math.Pi / 180.0
-	dLon := (lon2 - lon1) * math.Pi / 180.0
-
-	lat1 = lat1 * math.Pi / 180.0
-	lat2 = lat2 * math.Pi / 180.0
-
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Sin(dLon/2)*math.Sin(dLon/2)*math.Cos(lat1)*math.Cos(lat2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-
-	return R * c // Distance in km
-}
-
-// GetRoadDistance fetches the road distance using an external service (Google Maps, OpenStreetMap, etc.)
-func GetRoadDistance(lat1, lon1, lat2, lon2 float64) (float64, error) {
-	// Example implementation using OpenStreetMap's OSRM
-	baseURL := "http://router.project-osrm.org/route/v1/driving"
-	coords := fmt.Sprintf("%f,%f;%f,%f", lon1, lat1, lon2, lat2)
-	url := fmt.Sprintf("%s/%s?overview=false", baseURL, coords)
-
-	response, err := http.Get(url)
-	if err != nil {
-		return 0, fmt.Errorf("failed to fetch road distance: %v", err)
-	}
-	defer response.Body.Close()
-
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return 0, fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, fmt.Errorf("failed to parse JSON response: %v", err)
-	}
-
-	// Extract the distance
-	routes, ok := result["routes"].([]interface{})
-	if !ok || len(routes) == 0 {
-		return 0, fmt.Errorf("no routes found in response")
-	}
-
-	route, ok := routes[0].(map[string]interface{})
-	if !ok {
-		return 0, fmt.Errorf("invalid route format")
-	}
-
-	distance, ok := route["distance"].(float64)
-	if !ok {
-		return 0, fmt.Errorf("distance not found in route")
-	}
-
-	return distance / 1000.0, nil // Convert to kilometers
-}
-
 // DistanceHandler calculates the distance between two points based on geohashes or coordinates
 func DistanceHandler(w http.ResponseWriter, r *http.Request) {
 	var request struct {
@@ -519,7 +491,7 @@ func DistanceHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Calculate the Haversine distance
-	haversineDistance := haversine(lat1, lon1, lat2, lon2)
+	haversineDistance := geohash.Haversine(lat1, lon1, lat2, lon2)
 
 	response := map[string]interface{}{
 		"haversine_distance_km": haversineDistance,
@@ -527,18 +499,36 @@ func DistanceHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Optionally, calculate the road distance if requested
 	if request.UseRoad {
-		roadDistance, err := GetRoadDistance(lat1, lon1, lat2, lon2)
+		route, err := routing.Default().Route(r.Context(), routing.Point{Lat: lat1, Lon: lon1}, routing.Point{Lat: lat2, Lon: lon2})
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to get road distance: %v", err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("Failed to get road distance: %v", err), http.StatusBadGateway)
 			return
 		}
-		response["road_distance_km"] = roadDistance
+		response["road_distance_km"] = route.DistanceKm()
+		response["road_duration_seconds"] = route.DurationSeconds
+		response["routing_provider"] = route.Provider
+		response["routing_cached"] = route.Cached
+		if route.Polyline != "" {
+			response["polyline"] = route.Polyline
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// RoutingCacheStats handles reporting the hit and miss counters of the route cache
+func RoutingCacheStats(w http.ResponseWriter, r *http.Request) {
+	stats, ok := routing.DefaultCacheStats()
+	if !ok {
+		http.Error(w, "Route cache is disabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
 // GeoIndexingHandler handles requests to find nearby points using geo-indexing techniques
 func GeoIndexingHandler(w http.ResponseWriter, r *http.Request) {
 	lat, err := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
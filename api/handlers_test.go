@@ -0,0 +1,203 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rider-assignment-system/cache"
+	"rider-assignment-system/database"
+	"rider-assignment-system/geofence"
+	"rider-assignment-system/internal/testutil"
+	"rider-assignment-system/models"
+	"sync"
+	"testing"
+)
+
+// setupIntegration connects to the throwaway Postgres and Redis named by the testutil
+// variables, empties both and returns the API's router. The test is skipped unless they are set.
+func setupIntegration(t *testing.T) http.Handler {
+	t.Helper()
+	cache.Rdb = testutil.Integration(t)
+
+	ctx := context.Background()
+	if err := geofence.LoadZones(ctx); err != nil {
+		t.Fatalf("failed to load zones: %v", err)
+	}
+	if _, err := cache.RebuildAvailability(ctx); err != nil {
+		t.Fatalf("failed to reset availability: %v", err)
+	}
+	return RegisterRoutes()
+}
+
+// postJSON sends a JSON request to the router and returns the recorded response
+func postJSON(router http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, path, bytes.NewReader(data)))
+	return recorder
+}
+
+func TestRequestRideConcurrentRequestsNeverShareADriver(t *testing.T) {
+	router := setupIntegration(t)
+
+	const drivers, riders = 3, 30
+	for i := 0; i < drivers; i++ {
+		response := postJSON(router, "POST", "/drivers", map[string]interface{}{
+			"name":      fmt.Sprintf("driver-%d", i),
+			"latitude":  52.52 + float64(i)*0.001,
+			"longitude": 13.405,
+			"status":    "available",
+		})
+		if response.Code != http.StatusOK {
+			t.Fatalf("creating driver %d: %d %s", i, response.Code, response.Body)
+		}
+	}
+	for i := 0; i < riders; i++ {
+		response := postJSON(router, "POST", "/riders", map[string]interface{}{"name": fmt.Sprintf("rider-%d", i)})
+		if response.Code != http.StatusOK {
+			t.Fatalf("creating rider %d: %d %s", i, response.Code, response.Body)
+		}
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, riders)
+	for i := 0; i < riders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response := postJSON(router, "POST", "/trips", map[string]interface{}{
+				"rider_id":        i + 1,
+				"start_latitude":  52.521,
+				"start_longitude": 13.406,
+				"end_latitude":    52.53,
+				"end_longitude":   13.42,
+			})
+			codes[i] = response.Code
+		}(i)
+	}
+	wg.Wait()
+
+	matched := 0
+	for i, code := range codes {
+		switch code {
+		case http.StatusOK:
+			matched++
+		case http.StatusNotFound: // no driver left
+		default:
+			t.Errorf("request %d: unexpected status %d", i, code)
+		}
+	}
+	if matched != drivers {
+		t.Errorf("matched %d trips, want one per driver (%d)", matched, drivers)
+	}
+
+	rows, err := database.DB.Query(
+		`SELECT driver_id, count(*) FROM trips
+         WHERE status IN ('driver_assigned', 'accepted', 'driver_arrived', 'in_progress')
+         GROUP BY driver_id HAVING count(*) > 1`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var driverID, trips int64
+		if err := rows.Scan(&driverID, &trips); err != nil {
+			t.Fatal(err)
+		}
+		t.Errorf("driver %d is in %d active trips", driverID, trips)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDriverStatusUpdateCannotReleaseAReservedDriver(t *testing.T) {
+	router := setupIntegration(t)
+
+	response := postJSON(router, "POST", "/drivers", map[string]interface{}{
+		"name": "driver", "latitude": 52.52, "longitude": 13.405, "status": "available",
+	})
+	if response.Code != http.StatusOK {
+		t.Fatalf("creating driver: %d %s", response.Code, response.Body)
+	}
+	response = postJSON(router, "POST", "/riders", map[string]interface{}{"name": "rider"})
+	if response.Code != http.StatusOK {
+		t.Fatalf("creating rider: %d %s", response.Code, response.Body)
+	}
+	response = postJSON(router, "POST", "/trips", map[string]interface{}{
+		"rider_id": 1, "start_latitude": 52.521, "start_longitude": 13.406, "end_latitude": 52.53, "end_longitude": 13.42,
+	})
+	if response.Code != http.StatusOK {
+		t.Fatalf("requesting ride: %d %s", response.Code, response.Body)
+	}
+
+	tests := []struct {
+		name, path string
+		body       map[string]interface{}
+		want       int
+	}{
+		{"status available", "/drivers/1/status", map[string]interface{}{"driver_id": 1, "status": "available"}, http.StatusConflict},
+		{"status offline", "/drivers/1/status", map[string]interface{}{"driver_id": 1, "status": "offline"}, http.StatusConflict},
+		{"unknown status", "/drivers/1/status", map[string]interface{}{"driver_id": 1, "status": "busy"}, http.StatusBadRequest},
+		{"location available", "/drivers/1/location", map[string]interface{}{
+			"driver_id": 1, "latitude": 52.52, "longitude": 13.405, "status": "available"}, http.StatusConflict},
+		{"location keeps status", "/drivers/1/location", map[string]interface{}{
+			"driver_id": 1, "latitude": 52.52, "longitude": 13.405}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		response := postJSON(router, "PUT", tt.path, tt.body)
+		if response.Code != tt.want {
+			t.Errorf("%s: got %d %s, want %d", tt.name, response.Code, response.Body, tt.want)
+		}
+	}
+
+	var status string
+	if err := database.DB.QueryRow(`SELECT status FROM drivers WHERE id=1`).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status != models.DriverReserved {
+		t.Errorf("driver status = %s, want %s", status, models.DriverReserved)
+	}
+}
+
+func TestDriverStatusUpdateChecksTheServiceArea(t *testing.T) {
+	router := setupIntegration(t)
+
+	response := postJSON(router, "POST", "/zones", map[string]interface{}{
+		"name": "berlin",
+		"kind": models.ZoneServiceArea,
+		"geometry": map[string]interface{}{
+			"type":        "Polygon",
+			"coordinates": [][][]float64{{{13.3, 52.4}, {13.5, 52.4}, {13.5, 52.6}, {13.3, 52.6}, {13.3, 52.4}}},
+		},
+	})
+	if response.Code != http.StatusCreated {
+		t.Fatalf("creating zone: %d %s", response.Code, response.Body)
+	}
+
+	drivers := []struct {
+		name     string
+		lat, lon float64
+		want     int
+	}{
+		{"inside", 52.52, 13.405, http.StatusOK},
+		{"outside", 48.85, 2.35, http.StatusUnprocessableEntity},
+	}
+	for i, d := range drivers {
+		response := postJSON(router, "POST", "/drivers", map[string]interface{}{
+			"name": d.name, "latitude": d.lat, "longitude": d.lon, "status": models.DriverOffline,
+		})
+		if response.Code != http.StatusOK {
+			t.Fatalf("creating driver %s: %d %s", d.name, response.Code, response.Body)
+		}
+		path := fmt.Sprintf("/drivers/%d/status", i+1)
+		response = postJSON(router, "PUT", path, map[string]interface{}{"driver_id": i + 1, "status": models.DriverAvailable})
+		if response.Code != d.want {
+			t.Errorf("driver %s going available: got %d %s, want %d", d.name, response.Code, response.Body, d.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"rider-assignment-system/config"
+	"rider-assignment-system/geohash"
+	"rider-assignment-system/routing"
+)
+
+// matrixPoint is a source or destination of a distance matrix, given as a geohash or coordinates
+type matrixPoint struct {
+	Geohash string  `json:"geohash"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+// point returns the location of p, decoding its geohash when set
+func (p matrixPoint) point() (routing.Point, error) {
+	if p.Geohash != "" {
+		lat, lon := geohash.Decode(p.Geohash)
+		return routing.Point{Lat: lat, Lon: lon}, nil
+	}
+	if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+		return routing.Point{}, fmt.Errorf("coordinates out of range: %f,%f", p.Lat, p.Lon)
+	}
+	return routing.Point{Lat: p.Lat, Lon: p.Lon}, nil
+}
+
+// DistanceMatrixHandler calculates the distances from every source to every destination in one
+// call, e.g. from N drivers to M pickups. Entry [i][j] of each matrix is from source i to
+// destination j; road entries are null when no route was found.
+func DistanceMatrixHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Sources      []matrixPoint `json:"sources"`
+		Destinations []matrixPoint `json:"destinations"`
+		UseRoad      bool          `json:"use_road"` // Optional: whether to calculate road distances and durations
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if len(request.Sources) == 0 || len(request.Destinations) == 0 {
+		http.Error(w, "Invalid input: provide at least one source and one destination", http.StatusBadRequest)
+		return
+	}
+	maxPoints := config.GetInt("routing.matrix.max_points", 100)
+	if len(request.Sources)+len(request.Destinations) > maxPoints {
+		http.Error(w, fmt.Sprintf("Too many points: at most %d sources and destinations in total", maxPoints), http.StatusBadRequest)
+		return
+	}
+
+	sources, err := matrixPoints(request.Sources)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid source: %v", err), http.StatusBadRequest)
+		return
+	}
+	destinations, err := matrixPoints(request.Destinations)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid destination: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	haversine := make([][]float64, len(sources))
+	for i, source := range sources {
+		haversine[i] = make([]float64, len(destinations))
+		for j, destination := range destinations {
+			haversine[i][j] = geohash.Haversine(source.Lat, source.Lon, destination.Lat, destination.Lon)
+		}
+	}
+
+	response := map[string]interface{}{
+		"sources":               sources,
+		"destinations":          destinations,
+		"haversine_distance_km": haversine,
+	}
+
+	// Optionally, calculate the road distances and durations if requested
+	if request.UseRoad {
+		concurrency := config.GetInt("routing.matrix.concurrency", 8)
+		matrix, err := routing.ComputeMatrix(r.Context(), routing.Default(), sources, destinations, concurrency)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get road distances: %v", err), http.StatusBadGateway)
+			return
+		}
+		roadKm := make([][]*float64, len(matrix.DistancesMeters))
+		for i, row := range matrix.DistancesMeters {
+			roadKm[i] = make([]*float64, len(row))
+			for j, meters := range row {
+				if meters != nil {
+					km := *meters / 1000
+					roadKm[i][j] = &km
+				}
+			}
+		}
+		response["road_distance_km"] = roadKm
+		response["road_duration_seconds"] = matrix.DurationsSeconds
+		response["routing_provider"] = matrix.Provider
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// matrixPoints resolves the locations of request points
+func matrixPoints(requested []matrixPoint) ([]routing.Point, error) {
+	points := make([]routing.Point, len(requested))
+	for i, p := range requested {
+		point, err := p.point()
+		if err != nil {
+			return nil, fmt.Errorf("point %d: %v", i, err)
+		}
+		points[i] = point
+	}
+	return points, nil
+}
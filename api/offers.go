@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rider-assignment-system/dispatch"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetDriverOffers handles listing the offers waiting on a driver's response
+func GetDriverOffers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	driverID, err := strconv.ParseInt(vars["driver_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid driver ID", http.StatusBadRequest)
+		return
+	}
+
+	offers, err := dispatch.PendingOffers(r.Context(), driverID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(offers)
+}
+
+// AcceptOffer handles a driver accepting a trip offer
+func AcceptOffer(w http.ResponseWriter, r *http.Request) {
+	driverID, offerID, ok := parseOfferVars(w, r)
+	if !ok {
+		return
+	}
+
+	offer, err := dispatch.AcceptOffer(r.Context(), driverID, offerID)
+	if err != nil {
+		writeDispatchError(w, err, "Failed to accept offer")
+		return
+	}
+
+	response := map[string]interface{}{
+		"message":  "Offer accepted",
+		"offer_id": offer.ID,
+		"trip_id":  offer.TripID,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeclineOffer handles a driver declining a trip offer; the trip is re-dispatched to the next-best driver
+func DeclineOffer(w http.ResponseWriter, r *http.Request) {
+	driverID, offerID, ok := parseOfferVars(w, r)
+	if !ok {
+		return
+	}
+
+	next, err := dispatch.DeclineOffer(r.Context(), driverID, offerID)
+	if err != nil {
+		writeDispatchError(w, err, "Failed to decline offer")
+		return
+	}
+
+	response := map[string]interface{}{
+		"message":  "Offer declined",
+		"offer_id": offerID,
+	}
+	if next != nil {
+		response["redispatched_to"] = next.Candidate.Driver.ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseOfferVars reads the driver and offer IDs from the URL, responding with 400 when invalid
+func parseOfferVars(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	vars := mux.Vars(r)
+	driverID, err := strconv.ParseInt(vars["driver_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid driver ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	offerID, err := strconv.ParseInt(vars["offer_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid offer ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return driverID, offerID, true
+}
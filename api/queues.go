@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rider-assignment-system/cache"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetQueue handles showing the drivers waiting in a queue zone, head of the queue first.
+// With ?driver_id= only that driver's place is returned.
+func GetQueue(w http.ResponseWriter, r *http.Request) {
+	zoneID, err := strconv.ParseInt(mux.Vars(r)["zone_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid zone ID", http.StatusBadRequest)
+		return
+	}
+
+	queued, err := cache.QueuedDrivers(r.Context(), zoneID)
+	if err != nil {
+		http.Error(w, "Failed to read queue", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if driverIDStr := r.URL.Query().Get("driver_id"); driverIDStr != "" {
+		driverID, err := strconv.ParseInt(driverIDStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid driver ID", http.StatusBadRequest)
+			return
+		}
+		for _, q := range queued {
+			if q.DriverID == driverID {
+				json.NewEncoder(w).Encode(q)
+				return
+			}
+		}
+		http.Error(w, "Driver is not in this queue", http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"zone_id": zoneID,
+		"length":  len(queued),
+		"drivers": queued,
+	}
+	json.NewEncoder(w).Encode(response)
+}
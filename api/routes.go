@@ -18,14 +18,37 @@ func RegisterRoutes() http.Handler {
 	router.HandleFunc("/drivers/{driver_id}", GetDriver).Methods("GET")
 	router.HandleFunc("/drivers/{driver_id}/status", DriverStatusUpdate).Methods("PUT")
 	router.HandleFunc("/drivers/{driver_id}/location", UpdateDriverLocation).Methods("PUT")
+	router.HandleFunc("/drivers/{driver_id}/offers", GetDriverOffers).Methods("GET")
+	router.HandleFunc("/drivers/{driver_id}/offers/{offer_id}/accept", AcceptOffer).Methods("PUT")
+	router.HandleFunc("/drivers/{driver_id}/offers/{offer_id}/decline", DeclineOffer).Methods("PUT")
 
 	// Trip endpoints
 	router.HandleFunc("/trips", RequestRide).Methods("POST")
 	router.HandleFunc("/trips/{trip_id}", GetTrip).Methods("GET")
+	router.HandleFunc("/trips/{trip_id}/accept", AcceptTrip).Methods("PUT")
+	router.HandleFunc("/trips/{trip_id}/arrive", DriverArrived).Methods("PUT")
+	router.HandleFunc("/trips/{trip_id}/start", StartTrip).Methods("PUT")
 	router.HandleFunc("/trips/{trip_id}/complete", CompleteTrip).Methods("PUT")
+	router.HandleFunc("/trips/{trip_id}/cancel", CancelTrip).Methods("PUT")
+	router.HandleFunc("/trips/{trip_id}/expire", ExpireTrip).Methods("PUT")
+
+	// Zone (geofence) endpoints
+	router.HandleFunc("/zones", CreateZone).Methods("POST")
+	router.HandleFunc("/zones", GetZones).Methods("GET")
+	router.HandleFunc("/zones/{zone_id}", DeleteZone).Methods("DELETE")
+	router.HandleFunc("/queues/{zone_id}", GetQueue).Methods("GET")
+
+	// Pricing endpoints
+	router.HandleFunc("/fares/estimate", EstimateFare).Methods("POST")
+	router.HandleFunc("/pricing/plans", CreatePricingPlan).Methods("POST")
+	router.HandleFunc("/pricing/plans", GetPricingPlans).Methods("GET")
+	router.HandleFunc("/surge", GetSurge).Methods("GET")
+	router.HandleFunc("/surge/heatmap", GetSurgeHeatmap).Methods("GET")
 
 	// Distance endpoint
 	router.HandleFunc("/distance", DistanceHandler).Methods("POST")
+	router.HandleFunc("/distance/matrix", DistanceMatrixHandler).Methods("POST")
+	router.HandleFunc("/routing/cache", RoutingCacheStats).Methods("GET")
 
 	// Add the GeoIndexingHandler route
 	router.HandleFunc("/geoindex", GeoIndexingHandler).Methods("GET")
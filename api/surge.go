@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rider-assignment-system/surge"
+	"strconv"
+)
+
+// GetSurge handles reporting the surge multiplier at a location
+func GetSurge(w http.ResponseWriter, r *http.Request) {
+	lat, err := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		http.Error(w, "Invalid latitude", http.StatusBadRequest)
+		return
+	}
+	lon, err := strconv.ParseFloat(r.URL.Query().Get("lon"), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		http.Error(w, "Invalid longitude", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(surge.MultiplierAt(r.Context(), lat, lon))
+}
+
+// GetSurgeHeatmap handles listing every surging cell with its demand, supply and multiplier
+func GetSurgeHeatmap(w http.ResponseWriter, r *http.Request) {
+	cells, computedAt, err := surge.Heatmap(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to load surge", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"precision": surge.Precision(),
+		"cells":     cells,
+	}
+	if !computedAt.IsZero() {
+		response["computed_at"] = computedAt
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
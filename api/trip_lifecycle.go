@@ -0,0 +1,156 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"rider-assignment-system/cache"
+	"rider-assignment-system/database"
+	"rider-assignment-system/dispatch"
+	"rider-assignment-system/models"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// AcceptTrip handles the driver accepting an assigned trip by resolving its pending offer
+func AcceptTrip(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tripIDStr := vars["trip_id"]
+	tripID, err := strconv.ParseInt(tripIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trip ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := dispatch.AcceptTripOffer(r.Context(), tripID); err != nil {
+		writeDispatchError(w, err, "Failed to accept trip")
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Trip accepted",
+		"trip_id": tripID,
+		"status":  models.TripAccepted,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DriverArrived handles the driver reaching the pickup point
+func DriverArrived(w http.ResponseWriter, r *http.Request) {
+	transitionTrip(w, r, models.TripDriverArrived)
+}
+
+// StartTrip handles the rider being picked up
+func StartTrip(w http.ResponseWriter, r *http.Request) {
+	transitionTrip(w, r, models.TripInProgress)
+}
+
+// CompleteTrip handles marking a trip as completed
+func CompleteTrip(w http.ResponseWriter, r *http.Request) {
+	transitionTrip(w, r, models.TripCompleted)
+}
+
+// CancelTrip handles the rider or driver cancelling a trip before it starts
+func CancelTrip(w http.ResponseWriter, r *http.Request) {
+	transitionTrip(w, r, models.TripCancelled)
+}
+
+// ExpireTrip handles marking a trip that was never picked up as expired
+func ExpireTrip(w http.ResponseWriter, r *http.Request) {
+	transitionTrip(w, r, models.TripExpired)
+}
+
+// transitionTrip moves the trip in the URL to the given status. Illegal transitions are
+// rejected with 409, and a trip reaching a terminal status frees its driver.
+func transitionTrip(w http.ResponseWriter, r *http.Request, to string) {
+	vars := mux.Vars(r)
+	tripIDStr := vars["trip_id"]
+	tripID, err := strconv.ParseInt(tripIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trip ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	var releasedDriver *models.Driver
+	err = database.WithTx(ctx, func(tx *sql.Tx) error {
+		var driverID sql.NullInt64
+		var status string
+		err := tx.QueryRow(
+			`SELECT driver_id, status FROM trips WHERE id=$1 FOR UPDATE`,
+			tripID,
+		).Scan(&driverID, &status)
+		if err == sql.ErrNoRows {
+			return &statusError{http.StatusNotFound, "Trip not found"}
+		}
+		if err != nil {
+			return err
+		}
+		if !models.CanTransition(status, to) {
+			return &statusError{http.StatusConflict, fmt.Sprintf("Cannot move trip from %s to %s", status, to)}
+		}
+
+		_, err = tx.Exec(
+			fmt.Sprintf(`UPDATE trips SET status=$1, %s=now() WHERE id=$2`, models.TripStatusTimestampColumn(to)),
+			to, tripID,
+		)
+		if err != nil {
+			return err
+		}
+
+		if !models.IsTerminalTripStatus(to) {
+			return nil
+		}
+
+		// Withdraw any offer still waiting on a driver
+		_, err = tx.Exec(
+			`UPDATE trip_offers SET status='cancelled', responded_at=now() WHERE trip_id=$1 AND status='pending'`,
+			tripID,
+		)
+		if err != nil || !driverID.Valid {
+			return err
+		}
+
+		// The trip is over; make its driver available again
+		var driver models.Driver
+		err = tx.QueryRow(
+			`UPDATE drivers SET status='available' WHERE id=$1
+             RETURNING id, name, latitude, longitude, geohash, status`,
+			driverID.Int64,
+		).Scan(
+			&driver.ID,
+			&driver.Name,
+			&driver.Latitude,
+			&driver.Longitude,
+			&driver.Geohash,
+			&driver.Status,
+		)
+		if err != nil {
+			return err
+		}
+		releasedDriver = &driver
+		return nil
+	})
+	if err != nil {
+		writeTxError(w, err, "Failed to update trip")
+		return
+	}
+
+	// Add driver back to Redis cache
+	if releasedDriver != nil && releasedDriver.Geohash != "" {
+		cache.ApplyUpdate("re-add available driver", func() error {
+			return cache.AddAvailableDriver(ctx, *releasedDriver)
+		})
+	}
+
+	response := map[string]interface{}{
+		"message": fmt.Sprintf("Trip %s", to),
+		"trip_id": tripID,
+		"status":  to,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
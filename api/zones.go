@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"rider-assignment-system/geofence"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// CreateZone handles uploading a geofence as a GeoJSON Polygon or MultiPolygon
+func CreateZone(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name     string          `json:"name"`
+		Kind     string          `json:"kind"`     // "service_area", "airport", "no_pickup" or "queue"
+		Geometry json.RawMessage `json:"geometry"` // GeoJSON geometry or Feature
+
+		// Optional: queue zone whose drivers are dispatched first in first out to pickups here
+		QueueZoneID *int64 `json:"queue_zone_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := geofence.ValidateZone(request.Name, request.Kind, request.Geometry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	zone, err := geofence.CreateZone(r.Context(), request.Name, request.Kind, request.Geometry, request.QueueZoneID)
+	if err == geofence.ErrInvalidQueueZone {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && strings.Contains(pgErr.Message, "duplicate key") {
+			http.Error(w, "Zone already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to create zone: %v", err)
+		http.Error(w, "Failed to create zone", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(zone)
+}
+
+// GetZones handles listing every zone
+func GetZones(w http.ResponseWriter, r *http.Request) {
+	zones, err := geofence.ListZones(r.Context())
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zones)
+}
+
+// DeleteZone handles removing a zone
+func DeleteZone(w http.ResponseWriter, r *http.Request) {
+	zoneID, err := strconv.ParseInt(mux.Vars(r)["zone_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid zone ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := geofence.DeleteZone(r.Context(), zoneID); err != nil {
+		if err == geofence.ErrZoneNotFound {
+			http.Error(w, "Zone not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to delete zone: %v", err)
+		http.Error(w, "Failed to delete zone", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]string{"message": "Zone deleted"}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,289 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"rider-assignment-system/config"
+	"rider-assignment-system/geohash"
+	"rider-assignment-system/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// AvailableDriversKey is the Redis GEO index of available drivers, keyed by driver ID.
+const AvailableDriversKey = "drivers:available"
+
+// NearbyDriver is an available driver found by a radius search.
+type NearbyDriver struct {
+	Driver         models.Driver
+	DistanceKm     float64
+	AvailableSince time.Time // zero when unknown
+}
+
+// cellKeyPrefix starts the key of every geohash cell set.
+const cellKeyPrefix = "drivers:cell:"
+
+// CellKey is the Redis set of available driver IDs in a geohash cell. Drivers are indexed at
+// every precision in geohash.IndexPrecisions so searches can start fine and coarsen.
+func CellKey(precision uint, cell string) string {
+	return fmt.Sprintf("%s%d:%s", cellKeyPrefix, precision, cell)
+}
+
+// cellField is the field of a driver's hash recording its cell at a precision, so the driver
+// can be taken out of that cell when it moves.
+func cellField(precision uint) string {
+	return fmt.Sprintf("cell:%d", precision)
+}
+
+// driverKeyPrefix starts the key of every driver's metadata hash.
+const driverKeyPrefix = "driver:"
+
+// driverKey is the Redis hash holding a driver's metadata.
+func driverKey(driverID int64) string {
+	return fmt.Sprintf("%s%d", driverKeyPrefix, driverID)
+}
+
+// AddAvailableDriver places a driver in the availability index at its current position,
+// replacing any previous position, and stores its metadata. The in-memory geo indexes are
+// updated alongside Redis, and the driver joins or leaves a queue zone's queue as they enter or
+// leave it.
+func AddAvailableDriver(ctx context.Context, driver models.Driver) error {
+	previous, err := indexedCells(ctx, driver.ID)
+	if err != nil {
+		return err
+	}
+	_, err = Rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for precision, cell := range previous {
+			pipe.SRem(ctx, CellKey(precision, cell), driver.ID)
+		}
+		addAvailableDriver(ctx, pipe, driver)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	geohash.IndexDriver(driver.ID, driver.Latitude, driver.Longitude)
+	return syncDriverQueue(ctx, driver)
+}
+
+// addAvailableDriver queues the commands that index a driver on pipe.
+func addAvailableDriver(ctx context.Context, pipe redis.Pipeliner, driver models.Driver) {
+	pipe.GeoAdd(ctx, AvailableDriversKey, &redis.GeoLocation{
+		Name:      strconv.FormatInt(driver.ID, 10),
+		Longitude: driver.Longitude,
+		Latitude:  driver.Latitude,
+	})
+	pipe.HSet(ctx, driverKey(driver.ID), "name", driver.Name, "geohash", driver.Geohash)
+	for _, precision := range geohash.IndexPrecisions() {
+		cell := geohash.Encode(driver.Latitude, driver.Longitude, precision)
+		pipe.SAdd(ctx, CellKey(precision, cell), driver.ID)
+		pipe.HSet(ctx, driverKey(driver.ID), cellField(precision), cell)
+	}
+	// Location updates must not reset how long the driver has been waiting for a trip
+	pipe.HSetNX(ctx, driverKey(driver.ID), "available_since", time.Now().Unix())
+}
+
+// RemoveAvailableDriver takes a driver out of the availability index and any queue.
+func RemoveAvailableDriver(ctx context.Context, driverID int64) error {
+	geohash.UnindexDriver(driverID)
+	if err := dequeueDriver(ctx, driverID); err != nil {
+		return err
+	}
+	previous, err := indexedCells(ctx, driverID)
+	if err != nil {
+		return err
+	}
+	_, err = Rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, AvailableDriversKey, strconv.FormatInt(driverID, 10))
+		pipe.HDel(ctx, driverKey(driverID), "available_since")
+		for precision, cell := range previous {
+			pipe.SRem(ctx, CellKey(precision, cell), driverID)
+			pipe.HDel(ctx, driverKey(driverID), cellField(precision))
+		}
+		return nil
+	})
+	return err
+}
+
+// indexedCells returns the cells a driver is currently indexed in, by precision.
+func indexedCells(ctx context.Context, driverID int64) (map[uint]string, error) {
+	precisions := geohash.IndexPrecisions()
+	fields := make([]string, len(precisions))
+	for i, precision := range precisions {
+		fields[i] = cellField(precision)
+	}
+	values, err := Rdb.HMGet(ctx, driverKey(driverID), fields...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read driver cells: %v", err)
+	}
+
+	cells := make(map[uint]string)
+	for i, value := range values {
+		if cell, ok := value.(string); ok && cell != "" {
+			cells[precisions[i]] = cell
+		}
+	}
+	return cells, nil
+}
+
+// SearchDriverCells returns the available drivers in the geohash cell around a point and its
+// eight neighbours at the given precision, closest first.
+func SearchDriverCells(ctx context.Context, lat, lon float64, precision uint) ([]NearbyDriver, error) {
+	center := geohash.Encode(lat, lon, precision)
+	cells := append(geohash.GetNeighbors(center), center)
+	keys := make([]string, len(cells))
+	for i, cell := range cells {
+		keys[i] = CellKey(precision, cell)
+	}
+
+	members, err := Rdb.SUnion(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search driver cells: %v", err)
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	positions, err := Rdb.GeoPos(ctx, AvailableDriversKey, members...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load driver positions: %v", err)
+	}
+	points := make([]geohash.DriverPoint, 0, len(members))
+	for i, member := range members {
+		driverID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil || positions[i] == nil {
+			continue // no longer in the availability index
+		}
+		points = append(points, geohash.DriverPoint{
+			ID:             driverID,
+			Lat:            positions[i].Latitude,
+			Lon:            positions[i].Longitude,
+			DistanceMeters: geohash.Haversine(lat, lon, positions[i].Latitude, positions[i].Longitude) * 1000,
+		})
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].DistanceMeters != points[j].DistanceMeters {
+			return points[i].DistanceMeters < points[j].DistanceMeters
+		}
+		return points[i].ID < points[j].ID
+	})
+	return withDriverMetadata(ctx, points)
+}
+
+// CountDriversByCell returns the number of available drivers in every non-empty geohash cell
+// of a precision, which must be one of geohash.IndexPrecisions.
+func CountDriversByCell(ctx context.Context, precision uint) (map[string]int64, error) {
+	prefix := CellKey(precision, "")
+	keys, err := scanKeys(ctx, prefix+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := Rdb.Pipeline()
+	cards := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cards[i] = pipe.SCard(ctx, key)
+	}
+	if len(keys) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to count drivers by cell: %v", err)
+		}
+	}
+
+	counts := make(map[string]int64, len(keys))
+	for i, key := range keys {
+		if n := cards[i].Val(); n > 0 {
+			counts[strings.TrimPrefix(key, prefix)] = n
+		}
+	}
+	return counts, nil
+}
+
+// SearchAvailableDrivers returns the available drivers within radiusKm of a point, closest first.
+// Candidates come from the Redis GEO index, or from the active in-memory index when
+// matching.candidate_source is "memory".
+func SearchAvailableDrivers(ctx context.Context, lat, lon, radiusKm float64) ([]NearbyDriver, error) {
+	if config.GetEnv("matching.candidate_source", "redis") == "memory" {
+		return searchIndexedDrivers(ctx, lat, lon, radiusKm)
+	}
+
+	locations, err := Rdb.GeoSearchLocation(ctx, AvailableDriversKey, &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{
+			Longitude:  lon,
+			Latitude:   lat,
+			Radius:     radiusKm,
+			RadiusUnit: "km",
+			Sort:       "ASC",
+		},
+		WithCoord: true,
+		WithDist:  true,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search available drivers: %v", err)
+	}
+	if len(locations) == 0 {
+		return nil, nil
+	}
+
+	points := make([]geohash.DriverPoint, 0, len(locations))
+	for _, loc := range locations {
+		driverID, err := strconv.ParseInt(loc.Name, 10, 64)
+		if err != nil {
+			continue
+		}
+		points = append(points, geohash.DriverPoint{
+			ID:             driverID,
+			Lat:            loc.Latitude,
+			Lon:            loc.Longitude,
+			DistanceMeters: loc.Dist * 1000,
+		})
+	}
+	return withDriverMetadata(ctx, points)
+}
+
+// searchIndexedDrivers finds candidates with the active in-memory geo index.
+func searchIndexedDrivers(ctx context.Context, lat, lon, radiusKm float64) ([]NearbyDriver, error) {
+	points := geohash.SearchDrivers(lat, lon, radiusKm, "")
+	if len(points) == 0 {
+		return nil, nil
+	}
+	return withDriverMetadata(ctx, points)
+}
+
+// withDriverMetadata loads the metadata of every point in one round trip, keeping their order.
+func withDriverMetadata(ctx context.Context, points []geohash.DriverPoint) ([]NearbyDriver, error) {
+	pipe := Rdb.Pipeline()
+	metadata := make([]*redis.StringStringMapCmd, len(points))
+	for i, point := range points {
+		metadata[i] = pipe.HGetAll(ctx, driverKey(point.ID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to load driver metadata: %v", err)
+	}
+
+	drivers := make([]NearbyDriver, 0, len(points))
+	for i, point := range points {
+		fields := metadata[i].Val()
+		var availableSince time.Time
+		if unix, err := strconv.ParseInt(fields["available_since"], 10, 64); err == nil {
+			availableSince = time.Unix(unix, 0)
+		}
+		drivers = append(drivers, NearbyDriver{
+			Driver: models.Driver{
+				ID:        point.ID,
+				Name:      fields["name"],
+				Latitude:  point.Lat,
+				Longitude: point.Lon,
+				Geohash:   fields["geohash"],
+				Status:    "available",
+			},
+			DistanceKm:     point.DistanceMeters / 1000,
+			AvailableSince: availableSince,
+		})
+	}
+	return drivers, nil
+}
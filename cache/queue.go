@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"rider-assignment-system/geofence"
+	"rider-assignment-system/models"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// QueuedDriver is a driver waiting in a queue zone.
+type QueuedDriver struct {
+	DriverID   int64     `json:"driver_id"`
+	Position   int       `json:"position"` // 1 for the head of the queue
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// queueField is the field of a driver's hash naming the queue zone they wait in.
+const queueField = "queue_zone"
+
+// queueKeyPrefix starts the key of every queue zone's queue.
+const queueKeyPrefix = "queue:zone:"
+
+// QueueKey is the Redis sorted set of the drivers waiting in a queue zone, scored by the time
+// they joined so the lowest score is the head of the queue.
+func QueueKey(zoneID int64) string {
+	return fmt.Sprintf("%s%d", queueKeyPrefix, zoneID)
+}
+
+// syncDriverQueue puts an available driver in the queue of the queue zone they are in, keeping
+// their place if they were already waiting there, and takes them out of any queue they left.
+func syncDriverQueue(ctx context.Context, driver models.Driver) error {
+	current, err := currentQueue(ctx, driver.ID)
+	if err != nil {
+		return err
+	}
+	zoneID := geofence.QueueZoneAt(driver.Latitude, driver.Longitude)
+	if zoneID != nil && current == *zoneID {
+		return nil
+	}
+
+	_, err = Rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if current != 0 {
+			pipe.ZRem(ctx, QueueKey(current), driver.ID)
+			pipe.HDel(ctx, driverKey(driver.ID), queueField)
+		}
+		if zoneID != nil {
+			pipe.ZAddNX(ctx, QueueKey(*zoneID), &redis.Z{Score: float64(time.Now().UnixMilli()), Member: driver.ID})
+			pipe.HSet(ctx, driverKey(driver.ID), queueField, *zoneID)
+		}
+		return nil
+	})
+	return err
+}
+
+// dequeueDriver takes a driver out of the queue they wait in, if any. A driver who stops being
+// available, for instance because they were offered a trip, loses their place.
+func dequeueDriver(ctx context.Context, driverID int64) error {
+	current, err := currentQueue(ctx, driverID)
+	if err != nil || current == 0 {
+		return err
+	}
+	_, err = Rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, QueueKey(current), driverID)
+		pipe.HDel(ctx, driverKey(driverID), queueField)
+		return nil
+	})
+	return err
+}
+
+// currentQueue returns the queue zone a driver waits in, or 0 when they are not queued.
+func currentQueue(ctx context.Context, driverID int64) (int64, error) {
+	value, err := Rdb.HGet(ctx, driverKey(driverID), queueField).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read driver queue: %v", err)
+	}
+	zoneID, _ := strconv.ParseInt(value, 10, 64)
+	return zoneID, nil
+}
+
+// QueuedDrivers returns the drivers waiting in a queue zone, head of the queue first.
+func QueuedDrivers(ctx context.Context, zoneID int64) ([]QueuedDriver, error) {
+	entries, err := Rdb.ZRangeWithScores(ctx, QueueKey(zoneID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue: %v", err)
+	}
+
+	queued := make([]QueuedDriver, 0, len(entries))
+	for i, entry := range entries {
+		member, _ := entry.Member.(string)
+		driverID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		queued = append(queued, QueuedDriver{
+			DriverID:   driverID,
+			Position:   i + 1,
+			EnqueuedAt: time.UnixMilli(int64(entry.Score)),
+		})
+	}
+	return queued, nil
+}
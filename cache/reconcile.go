@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"rider-assignment-system/database"
+	"rider-assignment-system/geofence"
+	"rider-assignment-system/geohash"
+	"rider-assignment-system/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ReconcileReport counts the repairs made by one reconciliation pass.
+type ReconcileReport struct {
+	Added   int // available in the database but missing from the index
+	Removed int // in the index but no longer available in the database
+}
+
+// RebuildAvailability replaces the availability index and the queue zone queues with every
+// available driver in the drivers table, and drops the metadata of drivers that are no longer
+// available. It is run at startup, after the zones are loaded, so drivers can be matched without
+// pinging again after a Redis restart, and returns the number of drivers indexed. Drivers who
+// stay in the same queue keep their place.
+func RebuildAvailability(ctx context.Context) (int, error) {
+	drivers, err := loadAvailableDrivers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	cellKeys, err := scanKeys(ctx, cellKeyPrefix+"*")
+	if err != nil {
+		return 0, err
+	}
+	driverKeys, err := scanKeys(ctx, driverKeyPrefix+"*")
+	if err != nil {
+		return 0, err
+	}
+	places, err := queuePlaces(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := float64(time.Now().UnixMilli())
+	_, err = Rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, AvailableDriversKey)
+		if len(cellKeys) > 0 {
+			pipe.Del(ctx, cellKeys...)
+		}
+		for key := range places {
+			pipe.Del(ctx, key)
+		}
+		for _, key := range driverKeys {
+			driverID, err := strconv.ParseInt(strings.TrimPrefix(key, driverKeyPrefix), 10, 64)
+			if err != nil || drivers[driverID].ID == 0 {
+				pipe.Del(ctx, key)
+			}
+		}
+		for _, driver := range drivers {
+			addAvailableDriver(ctx, pipe, driver)
+			pipe.HDel(ctx, driverKey(driver.ID), queueField)
+			zoneID := geofence.QueueZoneAt(driver.Latitude, driver.Longitude)
+			if zoneID == nil {
+				continue
+			}
+			key := QueueKey(*zoneID)
+			score, queued := places[key][strconv.FormatInt(driver.ID, 10)]
+			if !queued {
+				score = now
+			}
+			pipe.ZAdd(ctx, key, &redis.Z{Score: score, Member: driver.ID})
+			pipe.HSet(ctx, driverKey(driver.ID), queueField, *zoneID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild availability index: %v", err)
+	}
+
+	geohash.ResetDriverIndex()
+	for _, driver := range drivers {
+		geohash.IndexDriver(driver.ID, driver.Latitude, driver.Longitude)
+	}
+	return len(drivers), nil
+}
+
+// queuePlaces returns the score of every driver in every queue, by queue key and driver ID.
+func queuePlaces(ctx context.Context) (map[string]map[string]float64, error) {
+	keys, err := scanKeys(ctx, queueKeyPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	places := make(map[string]map[string]float64, len(keys))
+	for _, key := range keys {
+		entries, err := Rdb.ZRangeWithScores(ctx, key, 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read queue: %v", err)
+		}
+		places[key] = make(map[string]float64, len(entries))
+		for _, entry := range entries {
+			if member, ok := entry.Member.(string); ok {
+				places[key][member] = entry.Score
+			}
+		}
+	}
+	return places, nil
+}
+
+// ReconcileAvailability repairs drift between the drivers table and the availability index,
+// including the in-memory geo indexes. Every repair is made while holding a lock on the
+// driver's row and re-reading their status, so a driver reserved or released mid-pass is
+// never re-added or dropped.
+func ReconcileAvailability(ctx context.Context) (ReconcileReport, error) {
+	var report ReconcileReport
+
+	members, err := Rdb.ZRange(ctx, AvailableDriversKey, 0, -1).Result()
+	if err != nil {
+		return report, fmt.Errorf("failed to read availability index: %v", err)
+	}
+	drivers, err := loadAvailableDrivers(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	cached := make(map[int64]bool, len(members))
+	for _, member := range members {
+		driverID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			if err := Rdb.ZRem(ctx, AvailableDriversKey, member).Err(); err != nil {
+				return report, err
+			}
+			report.Removed++
+			continue
+		}
+		cached[driverID] = true
+		if drivers[driverID].ID != 0 {
+			continue
+		}
+		err = lockDriver(ctx, driverID, func(driver models.Driver) error {
+			if isIndexable(driver) {
+				return nil // released since the drivers were loaded
+			}
+			report.Removed++
+			return RemoveAvailableDriver(ctx, driverID)
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	for driverID := range drivers {
+		if cached[driverID] {
+			continue
+		}
+		err := lockDriver(ctx, driverID, func(driver models.Driver) error {
+			if !isIndexable(driver) {
+				return nil // reserved since the drivers were loaded
+			}
+			report.Added++
+			return AddAvailableDriver(ctx, driver)
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	indexed := make(map[int64]bool)
+	for _, driverID := range geohash.IndexedDriverIDs() {
+		indexed[driverID] = true
+		if drivers[driverID].ID != 0 {
+			continue
+		}
+		err := lockDriver(ctx, driverID, func(driver models.Driver) error {
+			if !isIndexable(driver) {
+				geohash.UnindexDriver(driverID)
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	for driverID := range drivers {
+		if indexed[driverID] {
+			continue
+		}
+		err := lockDriver(ctx, driverID, func(driver models.Driver) error {
+			if isIndexable(driver) {
+				geohash.IndexDriver(driver.ID, driver.Latitude, driver.Longitude)
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// lockDriver calls fn with the driver's current row while holding a share lock on it. Status
+// changes wait for the lock and update Redis only after they commit, so whatever fn does to the
+// index is applied before, never after, a concurrent reservation or release. A driver that no
+// longer exists is passed with only their ID set.
+func lockDriver(ctx context.Context, driverID int64, fn func(driver models.Driver) error) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to lock driver: %v", err)
+	}
+	defer tx.Rollback()
+
+	driver := models.Driver{ID: driverID}
+	err = tx.QueryRowContext(ctx,
+		`SELECT name, latitude, longitude, COALESCE(geohash, ''), status FROM drivers WHERE id=$1 FOR SHARE`,
+		driverID,
+	).Scan(&driver.Name, &driver.Latitude, &driver.Longitude, &driver.Geohash, &driver.Status)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to lock driver: %v", err)
+	}
+
+	if err := fn(driver); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isIndexable reports whether a driver belongs in the availability index.
+func isIndexable(driver models.Driver) bool {
+	return driver.Status == models.DriverAvailable && driver.Geohash != ""
+}
+
+// StartReconciler periodically repairs drift between the drivers table and the availability index.
+func StartReconciler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			report, err := ReconcileAvailability(context.Background())
+			if err != nil {
+				log.Printf("Availability reconciliation failed: %v", err)
+				continue
+			}
+			if report.Added > 0 || report.Removed > 0 {
+				log.Printf("Availability reconciliation: added %d, removed %d drivers", report.Added, report.Removed)
+			}
+		}
+	}()
+}
+
+// scanKeys returns every key matching pattern without blocking Redis the way KEYS does.
+func scanKeys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	iter := Rdb.Scan(ctx, 0, pattern, 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %v", pattern, err)
+	}
+	return keys, nil
+}
+
+// loadAvailableDrivers reads every available driver with a known location, keyed by ID.
+func loadAvailableDrivers(ctx context.Context) (map[int64]models.Driver, error) {
+	rows, err := database.DB.QueryContext(ctx,
+		`SELECT id, name, latitude, longitude, geohash FROM drivers
+         WHERE status=$1 AND geohash IS NOT NULL AND geohash <> ''`,
+		models.DriverAvailable,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load available drivers: %v", err)
+	}
+	defer rows.Close()
+
+	drivers := make(map[int64]models.Driver)
+	for rows.Next() {
+		driver := models.Driver{Status: models.DriverAvailable}
+		if err := rows.Scan(&driver.ID, &driver.Name, &driver.Latitude, &driver.Longitude, &driver.Geohash); err != nil {
+			return nil, err
+		}
+		drivers[driver.ID] = driver
+	}
+	return drivers, rows.Err()
+}
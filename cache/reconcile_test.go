@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"rider-assignment-system/database"
+	"rider-assignment-system/geofence"
+	"rider-assignment-system/geohash"
+	"rider-assignment-system/internal/testutil"
+	"rider-assignment-system/models"
+	"testing"
+	"time"
+)
+
+// setupIntegration connects to the throwaway Postgres and Redis named by the testutil
+// variables and empties both. The test is skipped unless they are set.
+func setupIntegration(t *testing.T) context.Context {
+	t.Helper()
+	Rdb = testutil.Integration(t)
+
+	ctx := context.Background()
+	if err := geofence.LoadZones(ctx); err != nil {
+		t.Fatalf("failed to load zones: %v", err)
+	}
+	geohash.ResetDriverIndex()
+	return ctx
+}
+
+// insertDriver stores a driver directly in the drivers table and returns it with its ID set.
+func insertDriver(t *testing.T, name string, lat, lon float64, status string) models.Driver {
+	t.Helper()
+	driver := models.Driver{Name: name, Latitude: lat, Longitude: lon, Geohash: geohash.Encode(lat, lon, 12), Status: status}
+	err := database.DB.QueryRow(
+		`INSERT INTO drivers (name, latitude, longitude, geohash, status) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		driver.Name, driver.Latitude, driver.Longitude, driver.Geohash, driver.Status,
+	).Scan(&driver.ID)
+	if err != nil {
+		t.Fatalf("failed to insert driver: %v", err)
+	}
+	return driver
+}
+
+func TestRebuildAvailabilityDropsStaleDriversAndRebuildsQueues(t *testing.T) {
+	ctx := setupIntegration(t)
+
+	square := json.RawMessage(`{"type":"Polygon","coordinates":[[[13.3,52.4],[13.5,52.4],[13.5,52.6],[13.3,52.6],[13.3,52.4]]]}`)
+	zone, err := geofence.CreateZone(ctx, "rank", models.ZoneQueue, square, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := geofence.LoadZones(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	first := insertDriver(t, "first", 52.5, 13.4, models.DriverAvailable)
+	second := insertDriver(t, "second", 52.51, 13.41, models.DriverAvailable)
+	offline := insertDriver(t, "offline", 52.52, 13.42, models.DriverAvailable)
+
+	// Index all three, then take one offline behind the cache's back and forget the queue
+	for _, driver := range []models.Driver{first, second, offline} {
+		if err := AddAvailableDriver(ctx, driver); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond) // distinct queue scores
+	}
+	if _, err := database.DB.Exec(`UPDATE drivers SET status=$1 WHERE id=$2`, models.DriverOffline, offline.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := Rdb.ZRem(ctx, QueueKey(zone.ID), second.ID).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Rdb.HSet(ctx, driverKey(9999), "name", "ghost").Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	indexed, err := RebuildAvailability(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexed != 2 {
+		t.Errorf("indexed %d drivers, want 2", indexed)
+	}
+
+	for _, driverID := range []int64{offline.ID, 9999} {
+		exists, err := Rdb.Exists(ctx, driverKey(driverID)).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != 0 {
+			t.Errorf("metadata of unavailable driver %d was kept", driverID)
+		}
+	}
+
+	queued, err := QueuedDrivers(ctx, zone.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 2 || queued[0].DriverID != first.ID || queued[1].DriverID != second.ID {
+		t.Fatalf("queue = %+v, want %d then %d", queued, first.ID, second.ID)
+	}
+	current, err := currentQueue(ctx, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != zone.ID {
+		t.Errorf("driver %d queue field = %d, want %d", second.ID, current, zone.ID)
+	}
+}
+
+func TestReconcileAvailabilityRepairsDrift(t *testing.T) {
+	ctx := setupIntegration(t)
+
+	missing := insertDriver(t, "missing", 52.5, 13.4, models.DriverAvailable)
+	stale := insertDriver(t, "stale", 52.51, 13.41, models.DriverAvailable)
+	if err := AddAvailableDriver(ctx, stale); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.DB.Exec(`UPDATE drivers SET status=$1 WHERE id=$2`, models.DriverReserved, stale.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ReconcileAvailability(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Added != 1 || report.Removed != 1 {
+		t.Errorf("report = %+v, want one added and one removed", report)
+	}
+	members, err := Rdb.ZRange(ctx, AvailableDriversKey, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0] != fmt.Sprint(missing.ID) {
+		t.Errorf("availability index = %v, want only %d", members, missing.ID)
+	}
+}
+
+func TestLockDriverHoldsOffReservations(t *testing.T) {
+	ctx := setupIntegration(t)
+	driver := insertDriver(t, "driver", 52.5, 13.4, models.DriverAvailable)
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- lockDriver(ctx, driver.ID, func(current models.Driver) error {
+			close(locked)
+			<-release
+			return AddAvailableDriver(ctx, current)
+		})
+	}()
+	<-locked
+
+	reserved := make(chan error, 1)
+	go func() {
+		_, err := database.DB.Exec(`UPDATE drivers SET status=$1 WHERE id=$2 AND status=$3`,
+			models.DriverReserved, driver.ID, models.DriverAvailable)
+		reserved <- err
+	}()
+	select {
+	case err := <-reserved:
+		t.Fatalf("reservation did not wait for the lock: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-reserved; err != nil {
+		t.Fatal(err)
+	}
+}
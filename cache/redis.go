@@ -42,3 +42,14 @@ func InitializeRedis() error {
 func GetRedisClient() *redis.Client {
 	return Rdb
 }
+
+// ApplyUpdate runs a Redis side-effect after its database transaction has committed.
+// The database is the source of truth, so a failure is retried once and then only logged.
+func ApplyUpdate(description string, update func() error) {
+	if err := update(); err == nil {
+		return
+	}
+	if err := update(); err != nil {
+		log.Printf("Failed to %s in cache: %v", description, err)
+	}
+}
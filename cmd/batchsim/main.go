@@ -0,0 +1,39 @@
+// Command batchsim compares the total pickup distance of greedy one-at-a-time matching with
+// batched minimum-cost matching over simulated peak-time demand.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"rider-assignment-system/matching"
+)
+
+func main() {
+	riders := flag.Int("riders", 50, "ride requests arriving within one batch window")
+	drivers := flag.Int("drivers", 60, "available drivers in the area")
+	sideKm := flag.Float64("side", 8, "side of the simulated square area in km")
+	radiusKm := flag.Float64("radius", 5, "maximum pickup distance in km")
+	runs := flag.Int("runs", 20, "number of simulated batches")
+	lat := flag.Float64("lat", 52.52, "latitude of the area centre")
+	lon := flag.Float64("lon", 13.405, "longitude of the area centre")
+	flag.Parse()
+
+	var greedyKm, optimalKm float64
+	var greedyMatched, optimalMatched int
+	for seed := int64(1); seed <= int64(*runs); seed++ {
+		result := matching.Simulate(*lat, *lon, *sideKm, *radiusKm, *riders, *drivers, seed)
+		greedyKm += result.GreedyPickupKm
+		optimalKm += result.OptimalPickupKm
+		greedyMatched += result.GreedyMatched
+		optimalMatched += result.OptimalMatched
+	}
+
+	fmt.Printf("%d runs of %d riders and %d drivers\n", *runs, *riders, *drivers)
+	fmt.Printf("greedy:  %6d matched, %10.2f km total pickup, %6.3f km per rider\n",
+		greedyMatched, greedyKm, greedyKm/float64(max(greedyMatched, 1)))
+	fmt.Printf("batched: %6d matched, %10.2f km total pickup, %6.3f km per rider\n",
+		optimalMatched, optimalKm, optimalKm/float64(max(optimalMatched, 1)))
+	if greedyKm > 0 && greedyMatched == optimalMatched {
+		fmt.Printf("batched matching saves %.1f%% of pickup distance\n", 100*(1-optimalKm/greedyKm))
+	}
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -12,7 +15,8 @@ func InitConfig() {
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath("./config")
 	viper.AddConfigPath(".")
-	viper.AutomaticEnv() // Override config values with environment variables
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv() // Override config values with environment variables (e.g. MATCHING_DISTANCE_SOURCE)
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Printf("No config file found: %v", err)
@@ -28,3 +32,65 @@ func GetEnv(key string, fallback string) string {
 	}
 	return fallback
 }
+
+// GetDuration fetches a duration setting such as "15s", with a fallback when unset or invalid
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	if value := viper.GetString(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		log.Printf("Invalid duration for %s: %q, using %s", key, value, fallback)
+	}
+	return fallback
+}
+
+// GetFloat fetches a numeric setting, with a fallback when unset
+func GetFloat(key string, fallback float64) float64 {
+	if !viper.IsSet(key) {
+		return fallback
+	}
+	return viper.GetFloat64(key)
+}
+
+// GetInt fetches an integer setting, with a fallback when unset
+func GetInt(key string, fallback int) int {
+	if !viper.IsSet(key) {
+		return fallback
+	}
+	return viper.GetInt(key)
+}
+
+// GetInts fetches a list of integers, such as a YAML sequence or "7,6,5" from the environment,
+// with a fallback when unset or empty
+func GetInts(key string, fallback []int) []int {
+	if !viper.IsSet(key) {
+		return fallback
+	}
+	raw, ok := viper.Get(key).(string)
+	if !ok {
+		if values := viper.GetIntSlice(key); len(values) > 0 {
+			return values
+		}
+		return fallback
+	}
+
+	var values []int
+	for _, field := range strings.Split(raw, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			log.Printf("Invalid integer list for %s: %q, using %v", key, raw, fallback)
+			return fallback
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// GetIntMap fetches a map of integers keyed by string, such as a YAML mapping
+func GetIntMap(key string) map[string]int {
+	values := make(map[string]int)
+	for name := range viper.GetStringMap(key) {
+		values[name] = viper.GetInt(key + "." + name)
+	}
+	return values
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestGetInt(t *testing.T) {
+	defer viper.Reset()
+
+	if got := GetInt("test.unset", 7); got != 7 {
+		t.Errorf("unset: got %d, want the fallback 7", got)
+	}
+	viper.Set("test.number", 12)
+	if got := GetInt("test.number", 7); got != 12 {
+		t.Errorf("number: got %d, want 12", got)
+	}
+	viper.Set("test.string", "42") // environment variables arrive as strings
+	if got := GetInt("test.string", 7); got != 42 {
+		t.Errorf("string: got %d, want 42", got)
+	}
+	viper.Set("test.zero", 0)
+	if got := GetInt("test.zero", 7); got != 0 {
+		t.Errorf("zero: got %d, want 0", got)
+	}
+}
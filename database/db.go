@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	_ "github.com/lib/pq" // PostgreSQL driver
@@ -43,3 +44,19 @@ func GetDB() *sql.DB {
 func Connect(dsn string) (*sql.DB, error) {
 	return sql.Open("postgres", dsn)
 }
+
+// WithTx runs fn inside a transaction, committing when fn succeeds and rolling back otherwise.
+func WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS trips_active_driver_idx;
@@ -0,0 +1,4 @@
+-- A driver can only be attached to one trip that has not been completed yet.
+CREATE UNIQUE INDEX IF NOT EXISTS trips_active_driver_idx
+    ON trips (driver_id)
+    WHERE status <> 'completed';
@@ -0,0 +1,18 @@
+-- Fold lifecycle statuses back onto the original 'requested'/'completed' pair
+UPDATE trips SET status = 'requested' WHERE status IN ('driver_assigned', 'accepted', 'driver_arrived', 'in_progress');
+UPDATE trips SET status = 'completed' WHERE status IN ('cancelled', 'expired');
+
+DROP INDEX IF EXISTS trips_active_driver_idx;
+CREATE UNIQUE INDEX trips_active_driver_idx
+    ON trips (driver_id)
+    WHERE status <> 'completed';
+
+ALTER TABLE trips
+    DROP COLUMN IF EXISTS requested_at,
+    DROP COLUMN IF EXISTS driver_assigned_at,
+    DROP COLUMN IF EXISTS accepted_at,
+    DROP COLUMN IF EXISTS driver_arrived_at,
+    DROP COLUMN IF EXISTS in_progress_at,
+    DROP COLUMN IF EXISTS completed_at,
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS expired_at;
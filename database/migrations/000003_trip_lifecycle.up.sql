@@ -0,0 +1,19 @@
+-- Record the time a trip entered each lifecycle status
+ALTER TABLE trips
+    ADD COLUMN IF NOT EXISTS requested_at TIMESTAMPTZ DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS driver_assigned_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS accepted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS driver_arrived_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS in_progress_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS expired_at TIMESTAMPTZ;
+
+-- Trips created before the lifecycle existed were assigned a driver on request
+UPDATE trips SET status = 'driver_assigned' WHERE status = 'requested' AND driver_id IS NOT NULL;
+
+-- Cancelled and expired trips no longer hold their driver
+DROP INDEX IF EXISTS trips_active_driver_idx;
+CREATE UNIQUE INDEX trips_active_driver_idx
+    ON trips (driver_id)
+    WHERE status NOT IN ('completed', 'cancelled', 'expired');
@@ -0,0 +1,16 @@
+-- Create the trip_offers table
+CREATE TABLE IF NOT EXISTS trip_offers (
+    id SERIAL PRIMARY KEY,
+    trip_id INT NOT NULL REFERENCES trips(id),
+    driver_id INT NOT NULL REFERENCES drivers(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'accepted', 'declined', 'expired', 'cancelled'
+    distance_km DOUBLE PRECISION,
+    eta_seconds DOUBLE PRECISION,
+    offered_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    responded_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS trip_offers_trip_idx ON trip_offers (trip_id);
+CREATE INDEX IF NOT EXISTS trip_offers_pending_driver_idx ON trip_offers (driver_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS trip_offers_pending_expiry_idx ON trip_offers (expires_at) WHERE status = 'pending';
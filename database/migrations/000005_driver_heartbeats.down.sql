@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS drivers_available_last_seen_idx;
+UPDATE drivers SET status = 'available' WHERE status = 'offline';
+ALTER TABLE drivers DROP COLUMN IF EXISTS last_seen_at;
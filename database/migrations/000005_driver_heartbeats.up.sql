@@ -0,0 +1,6 @@
+-- Track when each driver last pinged; existing drivers count as seen now
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ DEFAULT now();
+
+COMMENT ON COLUMN drivers.status IS '''available'', ''reserved'', ''on_trip'', ''offline''';
+
+CREATE INDEX IF NOT EXISTS drivers_available_last_seen_idx ON drivers (last_seen_at) WHERE status = 'available';
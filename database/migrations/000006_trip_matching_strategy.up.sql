@@ -0,0 +1,2 @@
+-- Remember which matching strategy dispatched each trip so re-dispatch uses the same one
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS matching_strategy VARCHAR(32);
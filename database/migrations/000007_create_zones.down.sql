@@ -0,0 +1,3 @@
+ALTER TABLE trips DROP COLUMN IF EXISTS dropoff_zone_id;
+ALTER TABLE trips DROP COLUMN IF EXISTS pickup_zone_id;
+DROP TABLE IF EXISTS zones;
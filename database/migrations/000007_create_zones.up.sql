@@ -0,0 +1,12 @@
+-- Named geofences: the service area, airports and zones where pickups are not allowed
+CREATE TABLE IF NOT EXISTS zones (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    kind VARCHAR(20) NOT NULL, -- 'service_area', 'airport', 'no_pickup'
+    geometry JSONB NOT NULL,   -- GeoJSON Polygon or MultiPolygon
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- The zone each trip was picked up and dropped off in, if any
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS pickup_zone_id INT REFERENCES zones(id) ON DELETE SET NULL;
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS dropoff_zone_id INT REFERENCES zones(id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+DELETE FROM zones WHERE kind = 'queue';
+ALTER TABLE zones DROP COLUMN IF EXISTS queue_zone_id;
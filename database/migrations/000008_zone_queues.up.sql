@@ -0,0 +1,4 @@
+-- A terminal zone can name the staging-lot queue zone its pickups are dispatched from
+ALTER TABLE zones ADD COLUMN IF NOT EXISTS queue_zone_id INT REFERENCES zones(id) ON DELETE SET NULL;
+
+COMMENT ON COLUMN zones.kind IS '''service_area'', ''airport'', ''no_pickup'', ''queue''';
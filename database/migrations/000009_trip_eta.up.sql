@@ -0,0 +1,2 @@
+-- Road ETA from the assigned driver to the pickup when the trip was offered, if it was routed
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS eta_seconds DOUBLE PRECISION;
@@ -0,0 +1,4 @@
+ALTER TABLE trips DROP COLUMN IF EXISTS fare;
+ALTER TABLE trips DROP COLUMN IF EXISTS fare_estimate_id;
+DROP TABLE IF EXISTS fare_estimates;
+DROP TABLE IF EXISTS pricing_plans;
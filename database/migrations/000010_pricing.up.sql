@@ -0,0 +1,47 @@
+-- Pricing plans per zone and vehicle class; a plan without a zone applies everywhere else
+CREATE TABLE IF NOT EXISTS pricing_plans (
+    id SERIAL PRIMARY KEY,
+    zone_id INT REFERENCES zones(id) ON DELETE CASCADE,
+    vehicle_class VARCHAR(20) NOT NULL DEFAULT 'standard',
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    base_fare NUMERIC(10, 2) NOT NULL,
+    per_km NUMERIC(10, 2) NOT NULL,
+    per_minute NUMERIC(10, 2) NOT NULL,
+    booking_fee NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    minimum_fare NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS pricing_plans_zone_class_idx ON pricing_plans (COALESCE(zone_id, 0), vehicle_class);
+
+INSERT INTO pricing_plans (zone_id, vehicle_class, currency, base_fare, per_km, per_minute, booking_fee, minimum_fare)
+VALUES (NULL, 'standard', 'USD', 2.50, 1.20, 0.25, 1.00, 5.00)
+ON CONFLICT DO NOTHING;
+
+-- Upfront fare quotes; a trip that references one before it expires is charged its total
+CREATE TABLE IF NOT EXISTS fare_estimates (
+    id SERIAL PRIMARY KEY,
+    rider_id INT REFERENCES riders(id),
+    plan_id INT NOT NULL REFERENCES pricing_plans(id),
+    vehicle_class VARCHAR(20) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    start_latitude DOUBLE PRECISION NOT NULL,
+    start_longitude DOUBLE PRECISION NOT NULL,
+    end_latitude DOUBLE PRECISION NOT NULL,
+    end_longitude DOUBLE PRECISION NOT NULL,
+    distance_km DOUBLE PRECISION NOT NULL,
+    duration_seconds DOUBLE PRECISION NOT NULL,
+    routing_provider VARCHAR(20) NOT NULL,
+    base_fare NUMERIC(10, 2) NOT NULL,
+    distance_fare NUMERIC(10, 2) NOT NULL,
+    time_fare NUMERIC(10, 2) NOT NULL,
+    minimum_fare_adjustment NUMERIC(10, 2) NOT NULL,
+    booking_fee NUMERIC(10, 2) NOT NULL,
+    total NUMERIC(10, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    trip_id INT UNIQUE REFERENCES trips(id) -- set once a trip locks the price
+);
+
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS fare_estimate_id INT REFERENCES fare_estimates(id);
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS fare NUMERIC(10, 2);
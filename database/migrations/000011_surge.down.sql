@@ -0,0 +1,3 @@
+ALTER TABLE trips DROP COLUMN IF EXISTS surge_multiplier;
+ALTER TABLE fare_estimates DROP COLUMN IF EXISTS surge_fare;
+ALTER TABLE fare_estimates DROP COLUMN IF EXISTS surge_multiplier;
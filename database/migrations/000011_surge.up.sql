@@ -0,0 +1,6 @@
+-- Surge multiplier applied to each fare estimate, and the extra it added
+ALTER TABLE fare_estimates ADD COLUMN IF NOT EXISTS surge_multiplier NUMERIC(4, 2) NOT NULL DEFAULT 1;
+ALTER TABLE fare_estimates ADD COLUMN IF NOT EXISTS surge_fare NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+-- Surge multiplier at the pickup when the trip was requested, or the one its fare estimate locked in
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS surge_multiplier NUMERIC(4, 2);
@@ -0,0 +1,194 @@
+package dispatch
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"rider-assignment-system/config"
+	"rider-assignment-system/database"
+	"rider-assignment-system/geofence"
+	"rider-assignment-system/geohash"
+	"rider-assignment-system/matching"
+	"sync"
+	"time"
+)
+
+// batchEntry is a trip waiting for its zone's batch to be matched.
+type batchEntry struct {
+	tripID  int64
+	req     matching.Request
+	matcher matching.BatchMatcher
+	result  chan batchResult
+}
+
+type batchResult struct {
+	assignment *Assignment
+	err        error
+}
+
+// batcher collects trip requests per zone and strategy, and matches each batch together once
+// its window closes.
+type batcher struct {
+	mu    sync.Mutex
+	zones map[string][]*batchEntry
+}
+
+var requestBatcher = &batcher{zones: make(map[string][]*batchEntry)}
+
+// batchingEnabled reports whether trips are matched in batches instead of one at a time.
+func batchingEnabled() bool {
+	return config.GetEnv("dispatch.batch.enabled", "false") == "true"
+}
+
+// requestTripBatched creates the trip, adds it to its zone's batch and waits for the batch to
+// be matched. If no driver can be found the trip is expired and matching.ErrNoDriverAvailable
+// is returned, as in greedy mode.
+func requestTripBatched(ctx context.Context, req TripRequest, matcher matching.BatchMatcher) (*Assignment, error) {
+	var tripID int64
+	err := database.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		tripID, err = createTrip(tx, req, matcher)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &batchEntry{
+		tripID: tripID,
+		req: matching.Request{
+			PickupLat:  req.StartLat,
+			PickupLon:  req.StartLon,
+			DropoffLat: req.EndLat,
+			DropoffLon: req.EndLon,
+		},
+		matcher: matcher,
+		result:  make(chan batchResult, 1),
+	}
+	precision := uint(config.GetInt("dispatch.batch.zone_precision", 5))
+	zone := geohash.Encode(req.StartLat, req.StartLon, precision)
+	requestBatcher.enqueue(zone+"/"+matcher.Name(), entry)
+
+	select {
+	case res := <-entry.result:
+		return res.assignment, res.err
+	case <-ctx.Done():
+		// The batch still runs; the rider can follow the trip with GET /trips/{id}
+		return nil, ctx.Err()
+	}
+}
+
+// enqueue adds an entry to its batch, starting the batch window if it is the first.
+func (b *batcher) enqueue(batch string, entry *batchEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pending, open := b.zones[batch]
+	b.zones[batch] = append(pending, entry)
+	if !open {
+		window := config.GetDuration("dispatch.batch.window", 2*time.Second)
+		time.AfterFunc(window, func() { b.flush(batch) })
+	}
+}
+
+// flush closes a batch and matches it.
+func (b *batcher) flush(batch string) {
+	b.mu.Lock()
+	entries := b.zones[batch]
+	delete(b.zones, batch)
+	b.mu.Unlock()
+
+	matchBatch(context.Background(), entries)
+}
+
+// matchBatch assigns drivers to a batch of trips by solving a minimum-cost bipartite matching
+// over the cost their strategy gives each driver, which all trips in a batch share. Trips the
+// solution leaves unmatched, or whose driver was claimed elsewhere in the meantime, fall back to
+// one-at-a-time dispatch, as do pickups served by a queue.
+func matchBatch(ctx context.Context, entries []*batchEntry) {
+	// Gather each trip's candidates and index the distinct drivers as columns
+	columns := make(map[int64]int)
+	var drivers []int64
+	candidates := make([]map[int64]matching.Candidate, len(entries))
+	for i, entry := range entries {
+		candidates[i] = make(map[int64]matching.Candidate)
+		if geofence.QueueServing(entry.req.PickupLat, entry.req.PickupLon) != nil {
+			continue // dispatched from its queue, first in first out, when the batch falls back
+		}
+		found, err := matching.FindCandidates(entry.req.PickupLat, entry.req.PickupLon)
+		if err != nil && err != matching.ErrNoDriverAvailable {
+			log.Printf("Batch candidate search failed for trip %d: %v", entry.tripID, err)
+		}
+		for _, c := range entry.matcher.Rank(entry.req, found) {
+			candidates[i][c.Driver.ID] = c
+			if _, ok := columns[c.Driver.ID]; !ok {
+				columns[c.Driver.ID] = len(drivers)
+				drivers = append(drivers, c.Driver.ID)
+			}
+		}
+	}
+
+	cost := make([][]float64, len(entries))
+	for i := range entries {
+		cost[i] = make([]float64, len(drivers))
+		for j, driverID := range drivers {
+			if c, ok := candidates[i][driverID]; ok {
+				cost[i][j] = entries[i].matcher.Cost(entries[i].req, c)
+			} else {
+				cost[i][j] = matching.Unassignable
+			}
+		}
+	}
+	solution := matching.SolveAssignment(cost)
+
+	for i, entry := range entries {
+		var match *matching.Candidate
+		if solution != nil && solution[i] >= 0 {
+			c := candidates[i][drivers[solution[i]]]
+			match = &c
+		}
+		assignment, err := assignBatchedTrip(ctx, entry.tripID, match)
+		entry.result <- batchResult{assignment, err}
+	}
+}
+
+// assignBatchedTrip offers a batched trip to the driver chosen for it, or to the best driver
+// available when there is none or they were claimed elsewhere. A trip nobody can take expires.
+func assignBatchedTrip(ctx context.Context, tripID int64, match *matching.Candidate) (*Assignment, error) {
+	var assignment *Assignment
+	err := database.WithTx(ctx, func(tx *sql.Tx) error {
+		if match != nil {
+			claimed, err := matching.ClaimDriver(tx, match.Driver.ID)
+			if err != nil {
+				return err
+			}
+			if claimed {
+				assignment, err = offerTripTo(tx, tripID, *match)
+				return err
+			}
+		}
+
+		var err error
+		assignment, err = offerTrip(tx, tripID)
+		if err == matching.ErrNoDriverAvailable {
+			_, expireErr := tx.Exec(
+				`UPDATE trips SET status='expired', expired_at=now() WHERE id=$1 AND status='requested'`,
+				tripID,
+			)
+			if expireErr != nil {
+				return expireErr
+			}
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if assignment == nil {
+		return nil, matching.ErrNoDriverAvailable
+	}
+
+	hideDriver(ctx, assignment.Candidate.Driver)
+	return assignment, nil
+}
@@ -0,0 +1,256 @@
+package dispatch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"rider-assignment-system/cache"
+	"rider-assignment-system/config"
+	"rider-assignment-system/database"
+	"rider-assignment-system/geofence"
+	"rider-assignment-system/matching"
+	"rider-assignment-system/models"
+	"rider-assignment-system/pricing"
+	"rider-assignment-system/surge"
+	"time"
+)
+
+var (
+	// ErrTripNotFound is returned when the trip being dispatched does not exist.
+	ErrTripNotFound = errors.New("trip not found")
+	// ErrOfferNotFound is returned when an offer does not exist or belongs to another driver.
+	ErrOfferNotFound = errors.New("offer not found")
+	// ErrOfferNotPending is returned when an offer was already accepted, declined or has expired.
+	ErrOfferNotPending = errors.New("offer is no longer pending")
+)
+
+// TripRequest is a rider's request for a ride between two points.
+type TripRequest struct {
+	RiderID  int64
+	StartLat float64
+	StartLon float64
+	EndLat   float64
+	EndLon   float64
+	Strategy string // matching strategy; empty selects the configured default
+
+	// FareEstimateID optionally names an upfront fare estimate whose total the trip is charged
+	FareEstimateID *int64
+
+	// Zones the trip starts and ends in, and the surge at its pickup, filled in by RequestTrip
+	pickupZoneID    *int64
+	dropoffZoneID   *int64
+	surgeMultiplier float64
+}
+
+// Assignment is a trip together with the offer currently held open for it.
+type Assignment struct {
+	TripID    int64
+	Offer     models.Offer
+	Candidate matching.Candidate
+
+	// Fare estimate the trip locked its price with, and that price; nil without one
+	FareEstimateID *int64
+	Fare           *float64
+
+	SurgeMultiplier *float64 // recorded on the trip for audit
+}
+
+// RequestTrip creates a trip and offers it to the best available driver. The trip is only
+// created when a driver could be reserved; otherwise matching.ErrNoDriverAvailable is returned.
+// Pickups and dropoffs outside the service area, and pickups in no_pickup zones, are rejected
+// with the geofence errors.
+//
+// When batching is enabled and the strategy can price drivers for a batch, the trip is instead
+// created straight away and matched together with the other requests in its zone that use the
+// same strategy at the end of the batch window.
+func RequestTrip(ctx context.Context, req TripRequest) (*Assignment, error) {
+	matcher, err := matching.GetMatcher(req.Strategy)
+	if err != nil {
+		return nil, err
+	}
+	if req.pickupZoneID, err = geofence.CheckPickup(req.StartLat, req.StartLon); err != nil {
+		return nil, err
+	}
+	if req.dropoffZoneID, err = geofence.CheckDropoff(req.EndLat, req.EndLon); err != nil {
+		return nil, err
+	}
+	surge.RecordDemand(ctx, req.StartLat, req.StartLon)
+	req.surgeMultiplier = surge.MultiplierAt(ctx, req.StartLat, req.StartLon).Multiplier
+	if batchMatcher, ok := matcher.(matching.BatchMatcher); ok && batchingEnabled() {
+		return requestTripBatched(ctx, req, batchMatcher)
+	}
+
+	var assignment *Assignment
+	err = database.WithTx(ctx, func(tx *sql.Tx) error {
+		tripID, err := createTrip(tx, req, matcher)
+		if err != nil {
+			return err
+		}
+
+		assignment, err = offerTrip(tx, tripID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hideDriver(ctx, assignment.Candidate.Driver)
+	return assignment, nil
+}
+
+// createTrip inserts a trip in the requested status with the surge at its pickup, or locks the
+// price and surge of its fare estimate if it names one.
+func createTrip(tx *sql.Tx, req TripRequest, matcher matching.Matcher) (int64, error) {
+	var tripID int64
+	err := tx.QueryRow(
+		`INSERT INTO trips (rider_id, start_latitude, start_longitude, end_latitude, end_longitude, status, requested_at,
+                            matching_strategy, pickup_zone_id, dropoff_zone_id, surge_multiplier)
+         VALUES ($1, $2, $3, $4, $5, 'requested', now(), $6, $7, $8, $9) RETURNING id`,
+		req.RiderID, req.StartLat, req.StartLon, req.EndLat, req.EndLon, matcher.Name(), req.pickupZoneID, req.dropoffZoneID,
+		req.surgeMultiplier,
+	).Scan(&tripID)
+	if err != nil || req.FareEstimateID == nil {
+		return tripID, err
+	}
+
+	estimate, err := pricing.LockEstimate(tx, *req.FareEstimateID, tripID, req.RiderID, req.StartLat, req.StartLon, req.EndLat, req.EndLon)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(
+		`UPDATE trips SET fare_estimate_id=$1, fare=$2, surge_multiplier=$3 WHERE id=$4`,
+		estimate.ID, estimate.Breakdown.Total, estimate.Breakdown.SurgeMultiplier, tripID,
+	)
+	return tripID, err
+}
+
+// offerTrip reserves the best driver, by the trip's matching strategy, who has not already been
+// offered the trip, records a pending offer for them and moves the trip to driver_assigned.
+// Pickups in a zone served by a queue go to the driver at the head of the queue instead, falling
+// back to the matching strategy when the queue is empty.
+func offerTrip(tx *sql.Tx, tripID int64) (*Assignment, error) {
+	var req matching.Request
+	var strategy sql.NullString
+	err := tx.QueryRow(
+		`SELECT start_latitude, start_longitude, end_latitude, end_longitude, matching_strategy FROM trips WHERE id=$1`,
+		tripID,
+	).Scan(&req.PickupLat, &req.PickupLon, &req.DropoffLat, &req.DropoffLon, &strategy)
+	if err == sql.ErrNoRows {
+		return nil, ErrTripNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := matching.GetMatcher(strategy.String)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := offeredDrivers(tx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if queueZoneID := geofence.QueueServing(req.PickupLat, req.PickupLon); queueZoneID != nil {
+		match, err := reserveFromQueue(tx, req, *queueZoneID, exclude)
+		if err != matching.ErrNoDriverAvailable {
+			if err != nil {
+				return nil, err
+			}
+			return offerTripTo(tx, tripID, *match)
+		}
+	}
+
+	match, err := matching.ReserveDriver(tx, matcher, req, exclude)
+	if err != nil {
+		return nil, err
+	}
+	return offerTripTo(tx, tripID, *match)
+}
+
+// reserveFromQueue claims the first available driver waiting in a queue zone.
+func reserveFromQueue(tx *sql.Tx, req matching.Request, queueZoneID int64, exclude map[int64]bool) (*matching.Candidate, error) {
+	queued, err := cache.QueuedDrivers(context.Background(), queueZoneID)
+	if err != nil {
+		return nil, err
+	}
+	driverIDs := make([]int64, len(queued))
+	for i, q := range queued {
+		driverIDs[i] = q.DriverID
+	}
+	return matching.ReserveFromQueue(tx, req, driverIDs, exclude)
+}
+
+// offerTripTo records a pending offer of the trip to an already reserved driver and moves the
+// trip to driver_assigned, along with the driver's ETA to the pickup when it is known.
+func offerTripTo(tx *sql.Tx, tripID int64, match matching.Candidate) (*Assignment, error) {
+	offer := models.Offer{
+		TripID:     tripID,
+		DriverID:   match.Driver.ID,
+		DistanceKm: match.DistanceKm,
+		ETASeconds: match.ETASeconds,
+	}
+	err := tx.QueryRow(
+		`INSERT INTO trip_offers (trip_id, driver_id, distance_km, eta_seconds, expires_at)
+         VALUES ($1, $2, $3, $4, now() + $5 * interval '1 millisecond')
+         RETURNING id, status, offered_at, expires_at`,
+		tripID, match.Driver.ID, match.DistanceKm, match.ETASeconds, offerTimeout().Milliseconds(),
+	).Scan(&offer.ID, &offer.Status, &offer.OfferedAt, &offer.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := &Assignment{TripID: tripID, Offer: offer, Candidate: match}
+	eta := sql.NullFloat64{Float64: match.ETASeconds, Valid: match.ETASeconds > 0}
+	err = tx.QueryRow(
+		`UPDATE trips SET driver_id=$1, status='driver_assigned', driver_assigned_at=now(), eta_seconds=$3 WHERE id=$2
+         RETURNING fare_estimate_id, fare, surge_multiplier`,
+		match.Driver.ID, tripID, eta,
+	).Scan(&assignment.FareEstimateID, &assignment.Fare, &assignment.SurgeMultiplier)
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// offeredDrivers returns the drivers that have already been offered the trip.
+func offeredDrivers(tx *sql.Tx, tripID int64) (map[int64]bool, error) {
+	rows, err := tx.Query(`SELECT driver_id FROM trip_offers WHERE trip_id=$1`, tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exclude := make(map[int64]bool)
+	for rows.Next() {
+		var driverID int64
+		if err := rows.Scan(&driverID); err != nil {
+			return nil, err
+		}
+		exclude[driverID] = true
+	}
+	return exclude, rows.Err()
+}
+
+// offerTimeout is how long a driver has to respond to an offer.
+func offerTimeout() time.Duration {
+	return config.GetDuration("dispatch.offer_timeout", 15*time.Second)
+}
+
+// hideDriver removes a driver holding an offer from the availability cache.
+func hideDriver(ctx context.Context, driver models.Driver) {
+	cache.ApplyUpdate("remove reserved driver", func() error {
+		return cache.RemoveAvailableDriver(ctx, driver.ID)
+	})
+}
+
+// showDriver returns a released driver to the availability cache.
+func showDriver(ctx context.Context, driver models.Driver) {
+	if driver.Geohash == "" {
+		return
+	}
+	cache.ApplyUpdate("re-add available driver", func() error {
+		return cache.AddAvailableDriver(ctx, driver)
+	})
+}
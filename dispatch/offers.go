@@ -0,0 +1,298 @@
+package dispatch
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"rider-assignment-system/database"
+	"rider-assignment-system/matching"
+	"rider-assignment-system/models"
+	"time"
+)
+
+// PendingOffers returns the offers currently waiting on the driver's response.
+func PendingOffers(ctx context.Context, driverID int64) ([]models.Offer, error) {
+	rows, err := database.DB.QueryContext(ctx,
+		`SELECT o.id, o.trip_id, o.driver_id, o.status, o.distance_km, o.eta_seconds,
+                t.start_latitude, t.start_longitude, t.end_latitude, t.end_longitude,
+                o.offered_at, o.expires_at
+         FROM trip_offers o JOIN trips t ON t.id = o.trip_id
+         WHERE o.driver_id=$1 AND o.status='pending' AND o.expires_at > now()
+         ORDER BY o.offered_at`,
+		driverID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offers := []models.Offer{}
+	for rows.Next() {
+		var offer models.Offer
+		var eta sql.NullFloat64
+		err := rows.Scan(
+			&offer.ID,
+			&offer.TripID,
+			&offer.DriverID,
+			&offer.Status,
+			&offer.DistanceKm,
+			&eta,
+			&offer.StartLat,
+			&offer.StartLon,
+			&offer.EndLat,
+			&offer.EndLon,
+			&offer.OfferedAt,
+			&offer.ExpiresAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		offer.ETASeconds = eta.Float64
+		offers = append(offers, offer)
+	}
+	return offers, rows.Err()
+}
+
+// AcceptOffer confirms a driver's pending offer, putting them on the trip.
+func AcceptOffer(ctx context.Context, driverID, offerID int64) (*models.Offer, error) {
+	var offer *models.Offer
+	err := database.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		offer, err = lockPendingOffer(tx, `o.id=$1`, offerID)
+		if err != nil {
+			return err
+		}
+		if offer.DriverID != driverID {
+			return ErrOfferNotFound
+		}
+		return acceptOffer(tx, offer)
+	})
+	return offer, err
+}
+
+// AcceptTripOffer confirms the pending offer of a trip on behalf of its driver.
+func AcceptTripOffer(ctx context.Context, tripID int64) (*models.Offer, error) {
+	var offer *models.Offer
+	err := database.WithTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM trips WHERE id=$1)`, tripID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrTripNotFound
+		}
+
+		var err error
+		offer, err = lockPendingOffer(tx, `o.trip_id=$1 AND o.status='pending'`, tripID)
+		if err == ErrOfferNotFound {
+			return ErrOfferNotPending
+		}
+		if err != nil {
+			return err
+		}
+		return acceptOffer(tx, offer)
+	})
+	return offer, err
+}
+
+// DeclineOffer releases the driver from a pending offer and offers the trip to the next-best
+// candidate. The returned assignment is nil when no other driver could take the trip, in which
+// case the trip has expired.
+func DeclineOffer(ctx context.Context, driverID, offerID int64) (*Assignment, error) {
+	return resolveOffer(ctx, offerID, driverID, models.OfferDeclined)
+}
+
+// ExpireOffers resolves every pending offer past its deadline and re-dispatches its trip.
+// It returns the number of offers expired.
+func ExpireOffers(ctx context.Context) (int, error) {
+	rows, err := database.DB.QueryContext(ctx,
+		`SELECT id FROM trip_offers WHERE status='pending' AND expires_at <= now()`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	var offerIDs []int64
+	for rows.Next() {
+		var offerID int64
+		if err := rows.Scan(&offerID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		offerIDs = append(offerIDs, offerID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, offerID := range offerIDs {
+		_, err := resolveOffer(ctx, offerID, 0, models.OfferExpired)
+		if err == ErrOfferNotPending {
+			continue // the driver responded while we were sweeping
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// StartOfferSweeper periodically expires offers that drivers did not respond to in time.
+func StartOfferSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			expired, err := ExpireOffers(context.Background())
+			if err != nil {
+				log.Printf("Failed to expire offers: %v", err)
+			}
+			if expired > 0 {
+				log.Printf("Expired %d driver offers", expired)
+			}
+		}
+	}()
+}
+
+// lockPendingOffer loads and row-locks the offer matching where. It fails with
+// ErrOfferNotFound when there is none and ErrOfferNotPending when it was already resolved.
+func lockPendingOffer(tx *sql.Tx, where string, arg interface{}) (*models.Offer, error) {
+	var offer models.Offer
+	var eta sql.NullFloat64
+	err := tx.QueryRow(
+		`SELECT o.id, o.trip_id, o.driver_id, o.status, o.distance_km, o.eta_seconds,
+                t.start_latitude, t.start_longitude, t.end_latitude, t.end_longitude,
+                o.offered_at, o.expires_at
+         FROM trip_offers o JOIN trips t ON t.id = o.trip_id
+         WHERE `+where+` FOR UPDATE OF o`,
+		arg,
+	).Scan(
+		&offer.ID,
+		&offer.TripID,
+		&offer.DriverID,
+		&offer.Status,
+		&offer.DistanceKm,
+		&eta,
+		&offer.StartLat,
+		&offer.StartLon,
+		&offer.EndLat,
+		&offer.EndLon,
+		&offer.OfferedAt,
+		&offer.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrOfferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	offer.ETASeconds = eta.Float64
+	if offer.Status != models.OfferPending {
+		return nil, ErrOfferNotPending
+	}
+	return &offer, nil
+}
+
+// acceptOffer marks a locked pending offer accepted and moves the driver and trip along with it.
+func acceptOffer(tx *sql.Tx, offer *models.Offer) error {
+	var expired bool
+	err := tx.QueryRow(
+		`UPDATE trip_offers SET status='accepted', responded_at=now() WHERE id=$1
+         RETURNING expires_at <= now()`,
+		offer.ID,
+	).Scan(&expired)
+	if err != nil {
+		return err
+	}
+	if expired {
+		return ErrOfferNotPending
+	}
+
+	result, err := tx.Exec(
+		`UPDATE trips SET status='accepted', accepted_at=now()
+         WHERE id=$1 AND driver_id=$2 AND status='driver_assigned'`,
+		offer.TripID, offer.DriverID,
+	)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows != 1 {
+		return ErrOfferNotPending
+	}
+
+	if _, err := tx.Exec(`UPDATE drivers SET status='on_trip' WHERE id=$1`, offer.DriverID); err != nil {
+		return err
+	}
+	offer.Status = models.OfferAccepted
+	return nil
+}
+
+// resolveOffer closes a pending offer without acceptance, frees its driver and offers the trip
+// to the next-best candidate, expiring the trip when nobody is left. A non-zero driverID must
+// match the offer's driver.
+func resolveOffer(ctx context.Context, offerID, driverID int64, status string) (*Assignment, error) {
+	var released models.Driver
+	var next *Assignment
+	err := database.WithTx(ctx, func(tx *sql.Tx) error {
+		offer, err := lockPendingOffer(tx, `o.id=$1`, offerID)
+		if err != nil {
+			return err
+		}
+		if driverID != 0 && offer.DriverID != driverID {
+			return ErrOfferNotFound
+		}
+
+		_, err = tx.Exec(`UPDATE trip_offers SET status=$1, responded_at=now() WHERE id=$2`, status, offer.ID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(
+			`UPDATE drivers SET status='available' WHERE id=$1 AND status='reserved'
+             RETURNING id, name, latitude, longitude, geohash, status`,
+			offer.DriverID,
+		).Scan(
+			&released.ID,
+			&released.Name,
+			&released.Latitude,
+			&released.Longitude,
+			&released.Geohash,
+			&released.Status,
+		)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		result, err := tx.Exec(
+			`UPDATE trips SET status='requested', driver_id=NULL, eta_seconds=NULL
+             WHERE id=$1 AND driver_id=$2 AND status='driver_assigned'`,
+			offer.TripID, offer.DriverID,
+		)
+		if err != nil {
+			return err
+		}
+		if rows, err := result.RowsAffected(); err != nil || rows != 1 {
+			return nil // the trip moved on (e.g. was cancelled); nothing to re-dispatch
+		}
+
+		next, err = offerTrip(tx, offer.TripID)
+		if err == matching.ErrNoDriverAvailable {
+			next = nil
+			_, err = tx.Exec(`UPDATE trips SET status='expired', expired_at=now() WHERE id=$1`, offer.TripID)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if released.ID != 0 {
+		showDriver(ctx, released)
+	}
+	if next != nil {
+		hideDriver(ctx, next.Candidate.Driver)
+	}
+	return next, nil
+}
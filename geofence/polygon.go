@@ -0,0 +1,145 @@
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Ring is a closed loop of [longitude, latitude] positions, as in GeoJSON.
+type Ring [][2]float64
+
+// Polygon is an outer ring followed by any number of holes.
+type Polygon []Ring
+
+// Shape is the area covered by a zone: one or more polygons.
+type Shape []Polygon
+
+// geometry is the part of a GeoJSON object needed to read polygons.
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geometry       `json:"geometry"` // set when the object is a Feature
+}
+
+// ParseGeoJSON reads a GeoJSON Polygon or MultiPolygon, bare or wrapped in a Feature, and
+// checks that every ring is closed and every position is a valid coordinate.
+func ParseGeoJSON(data []byte) (Shape, error) {
+	var g geometry
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %v", err)
+	}
+	if g.Type == "Feature" {
+		if g.Geometry == nil {
+			return nil, errors.New("GeoJSON feature has no geometry")
+		}
+		g = *g.Geometry
+	}
+
+	var shape Shape
+	switch g.Type {
+	case "Polygon":
+		var polygon Polygon
+		if err := json.Unmarshal(g.Coordinates, &polygon); err != nil {
+			return nil, fmt.Errorf("invalid Polygon coordinates: %v", err)
+		}
+		shape = Shape{polygon}
+	case "MultiPolygon":
+		if err := json.Unmarshal(g.Coordinates, &shape); err != nil {
+			return nil, fmt.Errorf("invalid MultiPolygon coordinates: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported GeoJSON type %q: expected Polygon or MultiPolygon", g.Type)
+	}
+
+	if len(shape) == 0 {
+		return nil, errors.New("geometry has no polygons")
+	}
+	for _, polygon := range shape {
+		if len(polygon) == 0 {
+			return nil, errors.New("polygon has no rings")
+		}
+		for _, ring := range polygon {
+			if err := ring.validate(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return shape, nil
+}
+
+// validate checks that a ring is closed and made of valid coordinates.
+func (ring Ring) validate() error {
+	if len(ring) < 4 {
+		return errors.New("a ring needs at least four positions")
+	}
+	if ring[0] != ring[len(ring)-1] {
+		return errors.New("a ring must end at its first position")
+	}
+	for _, p := range ring {
+		if p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+			return fmt.Errorf("position [%v, %v] is not a valid longitude/latitude", p[0], p[1])
+		}
+	}
+	return nil
+}
+
+// Contains reports whether a point lies inside the shape: inside the outer ring of one of its
+// polygons and outside that polygon's holes.
+func (shape Shape) Contains(lat, lon float64) bool {
+	for _, polygon := range shape {
+		if polygon.contains(lat, lon) {
+			return true
+		}
+	}
+	return false
+}
+
+func (polygon Polygon) contains(lat, lon float64) bool {
+	if !polygon[0].contains(lat, lon) {
+		return false
+	}
+	for _, hole := range polygon[1:] {
+		if hole.contains(lat, lon) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains tests a point against a ring by casting a ray eastwards and counting the edges it
+// crosses.
+func (ring Ring) contains(lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Area returns the planar area of the shape in square degrees, used to tell which of two
+// overlapping zones is the more specific.
+func (shape Shape) Area() float64 {
+	total := 0.0
+	for _, polygon := range shape {
+		total += polygon[0].area()
+		for _, hole := range polygon[1:] {
+			total -= hole.area()
+		}
+	}
+	return total
+}
+
+// area returns the unsigned area of a ring by the shoelace formula.
+func (ring Ring) area() float64 {
+	sum := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		sum += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return math.Abs(sum) / 2
+}
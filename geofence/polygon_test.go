@@ -0,0 +1,108 @@
+package geofence
+
+import (
+	"strings"
+	"testing"
+)
+
+// square is a GeoJSON polygon ring around [minLon, maxLon] x [minLat, maxLat].
+func square(minLon, minLat, maxLon, maxLat float64) Ring {
+	return Ring{{minLon, minLat}, {maxLon, minLat}, {maxLon, maxLat}, {minLon, maxLat}, {minLon, minLat}}
+}
+
+func TestParseGeoJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		polygons int
+		err      string // substring of the expected error, empty for success
+	}{
+		{"polygon", `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}`, 1, ""},
+		{"polygon with hole", `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[4,4],[6,4],[6,6],[4,6],[4,4]]]}`, 1, ""},
+		{"multipolygon", `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,1],[0,0]]],[[[5,5],[6,5],[6,6],[5,6],[5,5]]]]}`, 2, ""},
+		{"feature", `{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}}`, 1, ""},
+		{"not json", `{"type":`, 0, "invalid GeoJSON"},
+		{"feature without geometry", `{"type":"Feature"}`, 0, "no geometry"},
+		{"point", `{"type":"Point","coordinates":[0,0]}`, 0, "unsupported GeoJSON type"},
+		{"bad coordinates", `{"type":"Polygon","coordinates":"nope"}`, 0, "invalid Polygon coordinates"},
+		{"no polygons", `{"type":"MultiPolygon","coordinates":[]}`, 0, "no polygons"},
+		{"no rings", `{"type":"MultiPolygon","coordinates":[[]]}`, 0, "no rings"},
+		{"too few positions", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[0,0]]]}`, 0, "at least four positions"},
+		{"open ring", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1]]]}`, 0, "must end at its first position"},
+		{"latitude out of range", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,91],[0,0]]]}`, 0, "not a valid longitude/latitude"},
+		{"longitude out of range", `{"type":"Polygon","coordinates":[[[0,0],[181,0],[1,1],[0,0]]]}`, 0, "not a valid longitude/latitude"},
+	}
+	for _, tt := range tests {
+		shape, err := ParseGeoJSON([]byte(tt.input))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(shape) != tt.polygons {
+			t.Errorf("%s: %d polygons, want %d", tt.name, len(shape), tt.polygons)
+		}
+	}
+}
+
+func TestShapeContains(t *testing.T) {
+	donut := Shape{Polygon{square(0, 0, 10, 10), square(4, 4, 6, 6)}}
+	islands := Shape{Polygon{square(0, 0, 1, 1)}, Polygon{square(5, 5, 6, 6)}}
+	triangle := Shape{Polygon{Ring{{0, 0}, {10, 0}, {0, 10}, {0, 0}}}}
+
+	tests := []struct {
+		name     string
+		shape    Shape
+		lat, lon float64
+		want     bool
+	}{
+		{"inside", donut, 2, 2, true},
+		{"outside", donut, 12, 2, false},
+		{"in the hole", donut, 5, 5, false},
+		{"between hole and outer ring", donut, 5, 8, true},
+		{"first island", islands, 0.5, 0.5, true},
+		{"second island", islands, 5.5, 5.5, true},
+		{"between islands", islands, 3, 3, false},
+		{"inside the hypotenuse", triangle, 4.9, 4.9, true},
+		{"outside the hypotenuse", triangle, 5.1, 5.1, false},
+		// Edges are half-open: west and south edges belong to the shape, east and north do not,
+		// so a point on the border between two adjacent zones falls in exactly one of them
+		{"west edge", donut, 2, 0, true},
+		{"south edge", donut, 0, 2, true},
+		{"east edge", donut, 2, 10, false},
+		{"north edge", donut, 10, 2, false},
+		{"hole's west edge", donut, 5, 4, false},
+		{"hole's east edge", donut, 5, 6, true},
+	}
+	for _, tt := range tests {
+		if got := tt.shape.Contains(tt.lat, tt.lon); got != tt.want {
+			t.Errorf("%s: Contains(%v, %v) = %v, want %v", tt.name, tt.lat, tt.lon, got, tt.want)
+		}
+	}
+}
+
+func TestAdjacentShapesShareBorderPointsOnce(t *testing.T) {
+	west := Shape{Polygon{square(0, 0, 5, 10)}}
+	east := Shape{Polygon{square(5, 0, 10, 10)}}
+	for lat := 0.5; lat < 10; lat += 0.5 {
+		if west.Contains(lat, 5) == east.Contains(lat, 5) {
+			t.Errorf("border point (%v, 5) is in both or neither shape", lat)
+		}
+	}
+}
+
+func TestShapeArea(t *testing.T) {
+	donut := Shape{Polygon{square(0, 0, 10, 10), square(4, 4, 6, 6)}}
+	if got := donut.Area(); got != 96 {
+		t.Errorf("Area() = %v, want 96", got)
+	}
+	islands := Shape{Polygon{square(0, 0, 1, 1)}, Polygon{square(5, 5, 7, 7)}}
+	if got := islands.Area(); got != 5 {
+		t.Errorf("Area() = %v, want 5", got)
+	}
+}
@@ -0,0 +1,260 @@
+package geofence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"rider-assignment-system/database"
+	"rider-assignment-system/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrOutsideServiceArea is returned for a location outside every service area zone.
+	ErrOutsideServiceArea = errors.New("location is outside the service area")
+	// ErrPickupNotAllowed is returned for a pickup inside a no_pickup zone.
+	ErrPickupNotAllowed = errors.New("pickups are not allowed at this location")
+	// ErrZoneNotFound is returned when a zone does not exist.
+	ErrZoneNotFound = errors.New("zone not found")
+	// ErrInvalidQueueZone is returned when a zone is linked to something other than a queue zone.
+	ErrInvalidQueueZone = errors.New("queue_zone_id must name a queue zone")
+)
+
+// loadedZone is a zone with its geometry parsed for containment checks.
+type loadedZone struct {
+	models.Zone
+	shape Shape
+	area  float64
+}
+
+var (
+	zones     []loadedZone // smallest area first, so the most specific zone is found first
+	zonesLock sync.RWMutex
+)
+
+// ValidateZone checks a zone before it is stored.
+func ValidateZone(name, kind string, geometry json.RawMessage) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("zone name is required")
+	}
+	if !models.IsZoneKind(kind) {
+		return fmt.Errorf("unknown zone kind %q: expected %q, %q, %q or %q",
+			kind, models.ZoneServiceArea, models.ZoneAirport, models.ZoneNoPickup, models.ZoneQueue)
+	}
+	_, err := ParseGeoJSON(geometry)
+	return err
+}
+
+// CreateZone stores a new zone and starts enforcing it. queueZoneID, when set, links the zone to
+// the queue zone that serves its pickups.
+func CreateZone(ctx context.Context, name, kind string, geometry json.RawMessage, queueZoneID *int64) (*models.Zone, error) {
+	if err := ValidateZone(name, kind, geometry); err != nil {
+		return nil, err
+	}
+	if queueZoneID != nil {
+		var queueKind string
+		err := database.DB.QueryRowContext(ctx, `SELECT kind FROM zones WHERE id=$1`, *queueZoneID).Scan(&queueKind)
+		if err == sql.ErrNoRows || (err == nil && queueKind != models.ZoneQueue) {
+			return nil, ErrInvalidQueueZone
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	zone := models.Zone{Name: name, Kind: kind, Geometry: geometry, QueueZoneID: queueZoneID}
+	err := database.DB.QueryRowContext(ctx,
+		`INSERT INTO zones (name, kind, geometry, queue_zone_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		name, kind, string(geometry), queueZoneID,
+	).Scan(&zone.ID, &zone.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &zone, LoadZones(ctx)
+}
+
+// DeleteZone removes a zone. Trips that were recorded in it keep a NULL zone ID.
+func DeleteZone(ctx context.Context, zoneID int64) error {
+	result, err := database.DB.ExecContext(ctx, `DELETE FROM zones WHERE id=$1`, zoneID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return ErrZoneNotFound
+	}
+	return LoadZones(ctx)
+}
+
+// ListZones returns every zone, ordered by ID.
+func ListZones(ctx context.Context) ([]models.Zone, error) {
+	rows, err := database.DB.QueryContext(ctx,
+		`SELECT id, name, kind, geometry, created_at, queue_zone_id FROM zones ORDER BY id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Zone{}
+	for rows.Next() {
+		var zone models.Zone
+		var geometry string
+		if err := rows.Scan(&zone.ID, &zone.Name, &zone.Kind, &geometry, &zone.CreatedAt, &zone.QueueZoneID); err != nil {
+			return nil, err
+		}
+		zone.Geometry = json.RawMessage(geometry)
+		list = append(list, zone)
+	}
+	return list, rows.Err()
+}
+
+// LoadZones replaces the zones enforced by this process with those in the database.
+func LoadZones(ctx context.Context) error {
+	list, err := ListZones(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load zones: %v", err)
+	}
+	setZones(list)
+	return nil
+}
+
+// setZones replaces the zones enforced by this process, skipping those with invalid geometry.
+func setZones(list []models.Zone) {
+	loaded := make([]loadedZone, 0, len(list))
+	for _, zone := range list {
+		shape, err := ParseGeoJSON(zone.Geometry)
+		if err != nil {
+			log.Printf("Skipping zone %d (%s): %v", zone.ID, zone.Name, err)
+			continue
+		}
+		loaded = append(loaded, loadedZone{Zone: zone, shape: shape, area: shape.Area()})
+	}
+	sort.SliceStable(loaded, func(i, j int) bool { return loaded[i].area < loaded[j].area })
+
+	zonesLock.Lock()
+	zones = loaded
+	zonesLock.Unlock()
+}
+
+// StartRefresher periodically reloads the zones, picking up changes made by other instances.
+func StartRefresher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := LoadZones(context.Background()); err != nil {
+				log.Printf("Zone refresh failed: %v", err)
+			}
+		}
+	}()
+}
+
+// ZonesAt returns the zones containing a location, most specific (smallest) first.
+func ZonesAt(lat, lon float64) []models.Zone {
+	zonesLock.RLock()
+	defer zonesLock.RUnlock()
+
+	var found []models.Zone
+	for _, zone := range zones {
+		if zone.shape.Contains(lat, lon) {
+			found = append(found, zone.Zone)
+		}
+	}
+	return found
+}
+
+// QueueZoneAt returns the queue zone containing a location, or nil when it is in none.
+func QueueZoneAt(lat, lon float64) *int64 {
+	for _, zone := range ZonesAt(lat, lon) {
+		if zone.Kind == models.ZoneQueue {
+			id := zone.ID
+			return &id
+		}
+	}
+	return nil
+}
+
+// QueueServing returns the queue zone linked to the most specific zone containing a pickup
+// that has one, or nil when pickups there are dispatched by proximity.
+func QueueServing(lat, lon float64) *int64 {
+	for _, zone := range ZonesAt(lat, lon) {
+		if zone.QueueZoneID != nil {
+			id := *zone.QueueZoneID
+			return &id
+		}
+	}
+	return nil
+}
+
+// CheckPickup verifies that a rider can be picked up at a location and returns the most
+// specific zone it lies in, or nil when it is in none.
+func CheckPickup(lat, lon float64) (*int64, error) {
+	found, err := checkServiceArea(lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	for _, zone := range found {
+		if zone.Kind == models.ZoneNoPickup {
+			return nil, ErrPickupNotAllowed
+		}
+	}
+	return mostSpecific(found), nil
+}
+
+// CheckDropoff verifies that a trip can end at a location and returns the most specific zone
+// it lies in, or nil when it is in none.
+func CheckDropoff(lat, lon float64) (*int64, error) {
+	found, err := checkServiceArea(lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	return mostSpecific(found), nil
+}
+
+// CheckServiceArea verifies that a location is inside the service area.
+func CheckServiceArea(lat, lon float64) error {
+	_, err := checkServiceArea(lat, lon)
+	return err
+}
+
+// checkServiceArea returns the zones containing a location, failing when service areas are
+// defined and none of them contains it. Without any service area zone every location is served.
+func checkServiceArea(lat, lon float64) ([]models.Zone, error) {
+	found := ZonesAt(lat, lon)
+	for _, zone := range found {
+		if zone.Kind == models.ZoneServiceArea {
+			return found, nil
+		}
+	}
+	if hasServiceArea() {
+		return nil, ErrOutsideServiceArea
+	}
+	return found, nil
+}
+
+// hasServiceArea reports whether any service area zone is defined.
+func hasServiceArea() bool {
+	zonesLock.RLock()
+	defer zonesLock.RUnlock()
+	for _, zone := range zones {
+		if zone.Kind == models.ZoneServiceArea {
+			return true
+		}
+	}
+	return false
+}
+
+// mostSpecific returns the ID of the first zone, or nil when there is none.
+func mostSpecific(found []models.Zone) *int64 {
+	if len(found) == 0 {
+		return nil
+	}
+	id := found[0].ID
+	return &id
+}
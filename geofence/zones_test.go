@@ -0,0 +1,137 @@
+package geofence
+
+import (
+	"encoding/json"
+	"fmt"
+	"rider-assignment-system/models"
+	"testing"
+)
+
+// zone builds a zone covering a [minLon, maxLon] x [minLat, maxLat] box.
+func zone(id int64, kind string, minLon, minLat, maxLon, maxLat float64) models.Zone {
+	geometry := fmt.Sprintf(`{"type":"Polygon","coordinates":[[[%v,%v],[%v,%v],[%v,%v],[%v,%v],[%v,%v]]]}`,
+		minLon, minLat, maxLon, minLat, maxLon, maxLat, minLon, maxLat, minLon, minLat)
+	return models.Zone{ID: id, Name: fmt.Sprintf("zone %d", id), Kind: kind, Geometry: json.RawMessage(geometry)}
+}
+
+// useZones enforces the zones for the rest of the test.
+func useZones(t *testing.T, list ...models.Zone) {
+	t.Helper()
+	setZones(list)
+	t.Cleanup(func() { setZones(nil) })
+}
+
+func TestValidateZone(t *testing.T) {
+	geometry := json.RawMessage(`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`)
+	tests := []struct {
+		name, zoneName, kind string
+		geometry             json.RawMessage
+		wantErr              bool
+	}{
+		{"service area", "city", models.ZoneServiceArea, geometry, false},
+		{"airport", "airport", models.ZoneAirport, geometry, false},
+		{"no pickup", "stadium", models.ZoneNoPickup, geometry, false},
+		{"queue", "rank", models.ZoneQueue, geometry, false},
+		{"blank name", "  ", models.ZoneServiceArea, geometry, true},
+		{"unknown kind", "city", "parking", geometry, true},
+		{"bad geometry", "city", models.ZoneServiceArea, json.RawMessage(`{"type":"Point","coordinates":[0,0]}`), true},
+	}
+	for _, tt := range tests {
+		err := ValidateZone(tt.zoneName, tt.kind, tt.geometry)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateZone() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSetZonesSkipsInvalidGeometry(t *testing.T) {
+	broken := models.Zone{ID: 2, Name: "broken", Kind: models.ZoneServiceArea, Geometry: json.RawMessage(`{}`)}
+	useZones(t, zone(1, models.ZoneAirport, 0, 0, 1, 1), broken)
+	if found := ZonesAt(0.5, 0.5); len(found) != 1 || found[0].ID != 1 {
+		t.Errorf("ZonesAt = %+v, want only zone 1", found)
+	}
+	if hasServiceArea() {
+		t.Error("the broken service area is enforced")
+	}
+}
+
+func TestZonesAtOrdersMostSpecificFirst(t *testing.T) {
+	useZones(t,
+		zone(1, models.ZoneServiceArea, 0, 0, 10, 10),
+		zone(2, models.ZoneAirport, 2, 2, 4, 4),
+		zone(3, models.ZoneNoPickup, 2.5, 2.5, 3, 3),
+	)
+	found := ZonesAt(2.7, 2.7)
+	if len(found) != 3 || found[0].ID != 3 || found[1].ID != 2 || found[2].ID != 1 {
+		t.Errorf("ZonesAt = %+v, want zones 3, 2, 1", found)
+	}
+}
+
+func TestCheckPickupAndDropoff(t *testing.T) {
+	queueID := int64(4)
+	airport := zone(2, models.ZoneAirport, 2, 2, 4, 4)
+	airport.QueueZoneID = &queueID
+	useZones(t,
+		zone(1, models.ZoneServiceArea, 0, 0, 10, 10),
+		airport,
+		zone(3, models.ZoneNoPickup, 6, 6, 7, 7),
+		zone(4, models.ZoneQueue, 3.5, 3.5, 3.9, 3.9),
+	)
+
+	tests := []struct {
+		name        string
+		lat, lon    float64
+		pickupZone  int64 // 0 for none
+		pickupErr   error
+		dropoffZone int64
+		dropoffErr  error
+	}{
+		{"service area", 1, 1, 1, nil, 1, nil},
+		{"airport", 2.5, 2.5, 2, nil, 2, nil},
+		{"no pickup", 6.5, 6.5, 0, ErrPickupNotAllowed, 3, nil},
+		{"queue inside the airport", 3.7, 3.7, 4, nil, 4, nil},
+		{"outside", 20, 20, 0, ErrOutsideServiceArea, 0, ErrOutsideServiceArea},
+	}
+	for _, tt := range tests {
+		pickup, err := CheckPickup(tt.lat, tt.lon)
+		if err != tt.pickupErr || zoneID(pickup) != tt.pickupZone {
+			t.Errorf("%s: CheckPickup = %v, %v, want %v, %v", tt.name, zoneID(pickup), err, tt.pickupZone, tt.pickupErr)
+		}
+		dropoff, err := CheckDropoff(tt.lat, tt.lon)
+		if err != tt.dropoffErr || zoneID(dropoff) != tt.dropoffZone {
+			t.Errorf("%s: CheckDropoff = %v, %v, want %v, %v", tt.name, zoneID(dropoff), err, tt.dropoffZone, tt.dropoffErr)
+		}
+		if err := CheckServiceArea(tt.lat, tt.lon); err != tt.dropoffErr {
+			t.Errorf("%s: CheckServiceArea = %v, want %v", tt.name, err, tt.dropoffErr)
+		}
+	}
+
+	if got := zoneID(QueueServing(2.5, 2.5)); got != queueID {
+		t.Errorf("QueueServing(airport) = %v, want %v", got, queueID)
+	}
+	if got := zoneID(QueueServing(1, 1)); got != 0 {
+		t.Errorf("QueueServing(service area) = %v, want none", got)
+	}
+	if got := zoneID(QueueZoneAt(3.7, 3.7)); got != queueID {
+		t.Errorf("QueueZoneAt(queue) = %v, want %v", got, queueID)
+	}
+}
+
+func TestEveryLocationIsServedWithoutServiceAreas(t *testing.T) {
+	useZones(t, zone(1, models.ZoneNoPickup, 0, 0, 1, 1))
+
+	if _, err := CheckDropoff(20, 20); err != nil {
+		t.Errorf("CheckDropoff outside every zone = %v, want nil", err)
+	}
+	if _, err := CheckPickup(0.5, 0.5); err != ErrPickupNotAllowed {
+		t.Errorf("CheckPickup in a no_pickup zone = %v, want %v", err, ErrPickupNotAllowed)
+	}
+}
+
+// zoneID dereferences a zone ID, returning 0 for nil.
+func zoneID(id *int64) int64 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
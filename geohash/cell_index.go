@@ -0,0 +1,124 @@
+package geohash
+
+import (
+	"math"
+	"sync"
+)
+
+// GeohashIndex indexes points by the geohash cell they fall in, at every precision up to its
+// own. Radius searches look up the cell around the centre and its eight neighbours, at a
+// precision coarse enough to cover the radius.
+type GeohashIndex struct {
+	precision uint
+	mu        sync.RWMutex
+	points    map[int64]DriverPoint
+	cells     map[string]map[int64]bool // by cell of any precision up to idx.precision
+}
+
+// NewGeohashIndex creates an empty geohash index storing cells at the given precision.
+func NewGeohashIndex(precision uint) *GeohashIndex {
+	return &GeohashIndex{
+		precision: precision,
+		points:    make(map[int64]DriverPoint),
+		cells:     make(map[string]map[int64]bool),
+	}
+}
+
+// Insert adds a point, moving it if it is already indexed
+func (idx *GeohashIndex) Insert(id int64, lat, lon float64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(id)
+	idx.points[id] = DriverPoint{ID: id, Lat: lat, Lon: lon}
+	cell := Encode(lat, lon, idx.precision)
+	for p := 1; p <= len(cell); p++ {
+		if idx.cells[cell[:p]] == nil {
+			idx.cells[cell[:p]] = make(map[int64]bool)
+		}
+		idx.cells[cell[:p]][id] = true
+	}
+}
+
+// Update moves a point, inserting it if needed
+func (idx *GeohashIndex) Update(id int64, lat, lon float64) {
+	idx.Insert(id, lat, lon)
+}
+
+// Remove deletes a point
+func (idx *GeohashIndex) Remove(id int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(id)
+}
+
+// removeLocked deletes a point; idx.mu must be held
+func (idx *GeohashIndex) removeLocked(id int64) {
+	old, ok := idx.points[id]
+	if !ok {
+		return
+	}
+	cell := Encode(old.Lat, old.Lon, idx.precision)
+	for p := 1; p <= len(cell); p++ {
+		delete(idx.cells[cell[:p]], id)
+		if len(idx.cells[cell[:p]]) == 0 {
+			delete(idx.cells, cell[:p])
+		}
+	}
+	delete(idx.points, id)
+}
+
+// WithinRadius returns the points within meters of a location, closest first
+func (idx *GeohashIndex) WithinRadius(lat, lon, meters float64) []DriverPoint {
+	// The neighbourhood of the centre cell covers the radius once a cell is at least as big as
+	// the circle's span in each direction
+	latDeg, lonDeg := radiusDegrees(lat, meters)
+	precision := idx.precision
+	for precision > 1 && !cellCovers(precision, latDeg, lonDeg) {
+		precision--
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var results []DriverPoint
+	add := func(point DriverPoint) {
+		point.DistanceMeters = distanceMeters(lat, lon, point.Lat, point.Lon)
+		if point.DistanceMeters <= meters {
+			results = append(results, point)
+		}
+	}
+	if !cellCovers(precision, latDeg, lonDeg) {
+		// Even the coarsest cells are too small, so every point is a candidate
+		for _, point := range idx.points {
+			add(point)
+		}
+	} else {
+		center := Encode(lat, lon, precision)
+		seen := make(map[string]bool, 9)
+		for _, cell := range append(GetNeighbors(center), center) {
+			if seen[cell] {
+				continue // neighbours repeat next to the poles
+			}
+			seen[cell] = true
+			for id := range idx.cells[cell] {
+				add(idx.points[id])
+			}
+		}
+	}
+	sortByDistance(results)
+	return results
+}
+
+// Nearest returns up to k points closest to a location
+func (idx *GeohashIndex) Nearest(lat, lon float64, k int) []DriverPoint {
+	return nearestByRadius(idx, lat, lon, k)
+}
+
+// cellCovers reports whether geohash cells of the given precision are at least latDeg tall
+// and lonDeg wide
+func cellCovers(precision uint, latDeg, lonDeg float64) bool {
+	latBits := (5 * precision) / 2
+	lonBits := 5*precision - latBits
+	return 180/math.Pow(2, float64(latBits)) >= latDeg && 360/math.Pow(2, float64(lonBits)) >= lonDeg
+}
@@ -0,0 +1,98 @@
+package geohash
+
+import "sync"
+
+const (
+	driverCellPrecision = 6  // geohash precision of the in-memory cell index
+	driverHexResolution = 10 // hexagonal grid resolution of the in-memory index (~1 km edges)
+)
+
+var (
+	driverIndexLock sync.RWMutex
+	driverIndexes   = map[GeoIndexingTechnique]SpatialIndex{
+		GeohashingTechnique: NewGeohashIndex(driverCellPrecision),
+		HexGridTechnique:    NewHexGridIndex(driverHexResolution),
+	}
+	driverPositions = make(map[int64]DriverPoint)
+)
+
+// registerIndex makes a technique's index searchable, replacing any previous one. It starts
+// with every driver already indexed by the other techniques.
+func registerIndex(technique GeoIndexingTechnique, index SpatialIndex) {
+	driverIndexLock.Lock()
+	defer driverIndexLock.Unlock()
+
+	for _, point := range driverPositions {
+		index.Insert(point.ID, point.Lat, point.Lon)
+	}
+	driverIndexes[technique] = index
+}
+
+// GetIndex returns the index of a technique, or the default technique when empty.
+func GetIndex(technique GeoIndexingTechnique) (SpatialIndex, bool) {
+	if technique == "" {
+		technique = defaultTechnique
+	}
+	driverIndexLock.RLock()
+	defer driverIndexLock.RUnlock()
+	index, ok := driverIndexes[technique]
+	return index, ok
+}
+
+// IndexDriver inserts a driver into every in-memory index, or moves it if already present.
+// All techniques are kept up to date so their results can be compared side by side.
+func IndexDriver(driverID int64, lat, lon float64) {
+	driverIndexLock.Lock()
+	defer driverIndexLock.Unlock()
+
+	for _, index := range driverIndexes {
+		index.Update(driverID, lat, lon)
+	}
+	driverPositions[driverID] = DriverPoint{ID: driverID, Lat: lat, Lon: lon}
+}
+
+// UnindexDriver removes a driver from every in-memory index.
+func UnindexDriver(driverID int64) {
+	driverIndexLock.Lock()
+	defer driverIndexLock.Unlock()
+
+	for _, index := range driverIndexes {
+		index.Remove(driverID)
+	}
+	delete(driverPositions, driverID)
+}
+
+// ResetDriverIndex empties the in-memory indexes, ready to be repopulated from scratch.
+func ResetDriverIndex() {
+	driverIndexLock.Lock()
+	defer driverIndexLock.Unlock()
+
+	for driverID := range driverPositions {
+		for _, index := range driverIndexes {
+			index.Remove(driverID)
+		}
+	}
+	driverPositions = make(map[int64]DriverPoint)
+}
+
+// IndexedDriverIDs returns the IDs of every driver in the in-memory indexes.
+func IndexedDriverIDs() []int64 {
+	driverIndexLock.RLock()
+	defer driverIndexLock.RUnlock()
+
+	ids := make([]int64, 0, len(driverPositions))
+	for id := range driverPositions {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// SearchDrivers returns the indexed drivers within radiusKm of a point, closest first, using
+// the given technique (the default technique when empty).
+func SearchDrivers(lat, lon, radiusKm float64, technique GeoIndexingTechnique) []DriverPoint {
+	index, ok := GetIndex(technique)
+	if !ok {
+		return nil
+	}
+	return index.WithinRadius(lat, lon, radiusKm*1000)
+}
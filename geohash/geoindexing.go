@@ -10,10 +10,10 @@ const (
 	GeohashingTechnique GeoIndexingTechnique = "geohashing"
 	RTreeTechnique      GeoIndexingTechnique = "rtree"
 	QuadtreeTechnique   GeoIndexingTechnique = "quadtree"
+	HexGridTechnique    GeoIndexingTechnique = "hexgrid"
 )
 
 var defaultTechnique = GeohashingTechnique
-var quadtreeInstance *Quadtree
 
 // SetDefaultTechnique sets the default geo-indexing technique
 func SetDefaultTechnique(technique GeoIndexingTechnique) {
@@ -22,47 +22,30 @@ func SetDefaultTechnique(technique GeoIndexingTechnique) {
 
 // InitializeGlobalQuadtree initializes a global Quadtree instance
 func InitializeGlobalQuadtree(bounds Bounds) {
-	quadtreeInstance = InitializeQuadtree(bounds)
+	registerIndex(QuadtreeTechnique, NewQuadtreeIndex(bounds))
 }
 
-// SearchNearbyWithRetries tries to find nearby points with a retry mechanism
-func SearchNearbyWithRetries(lat, lon float64, technique GeoIndexingTechnique, maxRetries int) ([]interface{}, error) {
+// SearchNearbyWithRetries finds the indexed drivers near a point, doubling the search radius
+// from 1 km on each retry until some are found
+func SearchNearbyWithRetries(lat, lon float64, technique GeoIndexingTechnique, maxRetries int) ([]DriverPoint, error) {
 	if technique == "" {
 		technique = defaultTechnique
 	}
+	index, ok := GetIndex(technique)
+	if !ok {
+		return nil, errors.New("unsupported geo-indexing technique")
+	}
 
-	radius := 1.0 // Initial search radius
-	var results []interface{}
+	radiusKm := 1.0 // Initial search radius
+	var results []DriverPoint
 
 	for i := 0; i < maxRetries; i++ {
-		switch technique {
-		case GeohashingTechnique:
-			geohash := Encode(lat, lon, 12)
-			neighbors := GetNeighbors(geohash)
-			for _, neighbor := range neighbors {
-				results = append(results, neighbor)
-			}
-		case RTreeTechnique:
-			rtreeResults := SearchNearbyInRTree(lat, lon, radius)
-			for _, item := range rtreeResults {
-				results = append(results, item)
-			}
-		case QuadtreeTechnique:
-			if quadtreeInstance != nil {
-				qtResults := quadtreeInstance.SearchNearbyInQuadtree(Point{lat, lon}, radius)
-				for _, point := range qtResults {
-					results = append(results, point)
-				}
-			}
-		default:
-			return nil, errors.New("unsupported geo-indexing technique")
-		}
-
+		results = index.WithinRadius(lat, lon, radiusKm*1000)
 		if len(results) > 0 {
 			break // If results are found, exit the loop
 		}
 
-		radius *= 2 // Increase the search radius for the next retry
+		radiusKm *= 2 // Increase the search radius for the next retry
 	}
 
 	if len(results) == 0 {
@@ -0,0 +1,134 @@
+package geohash
+
+import (
+	"math"
+	"sync"
+)
+
+// hexMaxRing caps the k-ring a radius search walks; wider searches scan every point instead
+const hexMaxRing = 64
+
+// HexGridIndex indexes points by the hexagonal cell they fall in. Radius searches walk the
+// k-ring of cells around the centre, in every longitude zone the radius reaches.
+type HexGridIndex struct {
+	resolution int
+	mu         sync.RWMutex
+	points     map[int64]DriverPoint
+	cells      map[HexCell]map[int64]bool
+}
+
+// NewHexGridIndex creates an empty hexagonal grid index storing cells at a resolution.
+func NewHexGridIndex(resolution int) *HexGridIndex {
+	return &HexGridIndex{
+		resolution: resolution,
+		points:     make(map[int64]DriverPoint),
+		cells:      make(map[HexCell]map[int64]bool),
+	}
+}
+
+// Insert adds a point, moving it if it is already indexed
+func (idx *HexGridIndex) Insert(id int64, lat, lon float64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(id)
+	idx.points[id] = DriverPoint{ID: id, Lat: lat, Lon: lon}
+	cell := HexEncode(lat, lon, idx.resolution)
+	if idx.cells[cell] == nil {
+		idx.cells[cell] = make(map[int64]bool)
+	}
+	idx.cells[cell][id] = true
+}
+
+// Update moves a point, inserting it if needed
+func (idx *HexGridIndex) Update(id int64, lat, lon float64) {
+	idx.Insert(id, lat, lon)
+}
+
+// Remove deletes a point
+func (idx *HexGridIndex) Remove(id int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(id)
+}
+
+// removeLocked deletes a point; idx.mu must be held
+func (idx *HexGridIndex) removeLocked(id int64) {
+	old, ok := idx.points[id]
+	if !ok {
+		return
+	}
+	cell := HexEncode(old.Lat, old.Lon, idx.resolution)
+	delete(idx.cells[cell], id)
+	if len(idx.cells[cell]) == 0 {
+		delete(idx.cells, cell)
+	}
+	delete(idx.points, id)
+}
+
+// WithinRadius returns the points within meters of a location, closest first
+func (idx *HexGridIndex) WithinRadius(lat, lon, meters float64) []DriverPoint {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var results []DriverPoint
+	collect := func(id int64) {
+		point := idx.points[id]
+		point.DistanceMeters = distanceMeters(lat, lon, point.Lat, point.Lon)
+		if point.DistanceMeters <= meters {
+			results = append(results, point)
+		}
+	}
+
+	if zones, k, ok := idx.searchRing(lat, lon, meters); ok {
+		for zone := range zones {
+			x, y := hexProject(lat, lon, zone)
+			q, r := hexRound(x, y, HexEdgeMeters(idx.resolution))
+			for _, cell := range HexKRing(HexCell{Resolution: idx.resolution, Zone: zone, Q: q, R: r}, k) {
+				for id := range idx.cells[cell] {
+					collect(id)
+				}
+			}
+		}
+	} else {
+		for id := range idx.points {
+			collect(id)
+		}
+	}
+	sortByDistance(results)
+	return results
+}
+
+// searchRing returns the zones a radius search must visit and the k-ring to walk in each. It
+// reports false when the search is too wide for a ring walk, or the projections too distorted
+// (the radius spans more than one zone's width), and every point should be scanned instead.
+func (idx *HexGridIndex) searchRing(lat, lon, meters float64) (map[int]bool, int, bool) {
+	_, lonDeg := radiusDegrees(lat, meters)
+	if lonDeg > hexZoneWidth {
+		return nil, 0, false
+	}
+
+	// Cell centres lie within one edge of the points they hold, two next to a zone boundary,
+	// and each ring step moves at least 1.5 edges; 20% covers the shear of projecting from a
+	// nearby zone
+	size := HexEdgeMeters(idx.resolution)
+	k := int(math.Ceil((meters*1.2 + 2*size) / (1.5 * size)))
+	if k > hexMaxRing {
+		return nil, 0, false
+	}
+
+	// Points near a zone boundary may belong to a cell of the neighbouring zone, so the zones
+	// either side of those the radius reaches are searched too
+	zones := make(map[int]bool)
+	for _, box := range searchBoxes(lat, lon, meters) {
+		for z := hexZone(box.MinX) - 1; z <= hexZone(box.MaxX)+1; z++ {
+			zones[(z+hexZones)%hexZones] = true
+		}
+	}
+	return zones, k, true
+}
+
+// Nearest returns up to k points closest to a location
+func (idx *HexGridIndex) Nearest(lat, lon float64, k int) []DriverPoint {
+	return nearestByRadius(idx, lat, lon, k)
+}
@@ -0,0 +1,196 @@
+package geohash
+
+import (
+	"fmt"
+	"math"
+)
+
+// The hexagonal grid tiles the Earth with pointy-top hexagons laid out on a sinusoidal
+// projection. To keep cells close to regular, the globe is cut into 6° longitude zones (as in
+// UTM) and each zone is projected around its own central meridian. Resolutions form a
+// hierarchy: every step halves the cell edge, and a cell's parent is the cell of the next
+// coarser resolution containing its centre.
+const (
+	hexZoneWidth     = 6.0       // degrees of longitude per zone
+	hexZones         = 60        // 360 / hexZoneWidth
+	hexBaseEdge      = 1000000.0 // edge length in meters at resolution 0
+	MaxHexResolution = 20
+)
+
+// HexCell identifies a hexagon of the grid.
+type HexCell struct {
+	Resolution int
+	Zone       int   // longitude zone whose projection the cell belongs to
+	Q, R       int64 // axial coordinates within the zone
+}
+
+// String formats the cell as "resolution/zone/q/r".
+func (c HexCell) String() string {
+	return fmt.Sprintf("%d/%d/%d/%d", c.Resolution, c.Zone, c.Q, c.R)
+}
+
+// ParseHexCell parses a cell formatted by HexCell.String.
+func ParseHexCell(s string) (HexCell, error) {
+	var c HexCell
+	if _, err := fmt.Sscanf(s, "%d/%d/%d/%d", &c.Resolution, &c.Zone, &c.Q, &c.R); err != nil {
+		return HexCell{}, fmt.Errorf("invalid hex cell %q: %v", s, err)
+	}
+	if c.Resolution < 0 || c.Resolution > MaxHexResolution || c.Zone < 0 || c.Zone >= hexZones {
+		return HexCell{}, fmt.Errorf("invalid hex cell %q", s)
+	}
+	return c, nil
+}
+
+// HexEdgeMeters returns the edge length, which is also the centre-to-corner distance, of the
+// cells at a resolution.
+func HexEdgeMeters(resolution int) float64 {
+	return hexBaseEdge / math.Pow(2, float64(resolution))
+}
+
+// HexEncode returns the cell containing a location at a resolution. Along a zone boundary the
+// hexagons of the two zones overlap and leave gaps, so cells belong to the zone their centre
+// lies in: a location whose hexagon is owned by its zone gets that hexagon, and any other
+// location gets the owned cell with the nearest centre among those around it in its zone and
+// the two beside it. Decoding a cell returned by HexEncode and encoding its centre gives the
+// cell back.
+func HexEncode(lat, lon float64, resolution int) HexCell {
+	zone := hexZone(lon)
+	cell := hexCellIn(lat, lon, zone, resolution)
+	if hexOwned(cell) {
+		return cell
+	}
+
+	best, bestMeters := cell, math.Inf(1)
+	for k := 1; k <= hexMaxOwnerRing && math.IsInf(bestMeters, 1); k++ {
+		for _, z := range []int{zone, (zone + hexZones - 1) % hexZones, (zone + 1) % hexZones} {
+			for _, candidate := range HexKRing(hexCellIn(lat, lon, z, resolution), k) {
+				if !hexOwned(candidate) {
+					continue
+				}
+				centerLat, centerLon := HexDecode(candidate)
+				meters := distanceMeters(lat, lon, centerLat, centerLon)
+				if meters < bestMeters || meters == bestMeters && candidate.String() < best.String() {
+					best, bestMeters = candidate, meters
+				}
+			}
+		}
+	}
+	return best
+}
+
+// hexMaxOwnerRing caps the rings HexEncode searches for an owned cell
+const hexMaxOwnerRing = 3
+
+// hexOwned reports whether a cell belongs to its zone: its centre lies in the zone, and encoding
+// the centre in the zone's projection gives the cell back.
+func hexOwned(c HexCell) bool {
+	lat, lon := HexDecode(c)
+	return hexZone(lon) == c.Zone && hexCellIn(lat, lon, c.Zone, c.Resolution) == c
+}
+
+// hexCellIn returns the cell of a zone's projection containing a location
+func hexCellIn(lat, lon float64, zone, resolution int) HexCell {
+	x, y := hexProject(lat, lon, zone)
+	q, r := hexRound(x, y, HexEdgeMeters(resolution))
+	return HexCell{Resolution: resolution, Zone: zone, Q: q, R: r}
+}
+
+// HexDecode returns the centre of a cell.
+func HexDecode(c HexCell) (lat, lon float64) {
+	size := HexEdgeMeters(c.Resolution)
+	x := size * (math.Sqrt(3)*float64(c.Q) + math.Sqrt(3)/2*float64(c.R))
+	y := size * 1.5 * float64(c.R)
+	return hexUnproject(x, y, c.Zone)
+}
+
+// Parent returns the cell of the next coarser resolution containing the cell's centre.
+func (c HexCell) Parent() HexCell {
+	if c.Resolution == 0 {
+		return c
+	}
+	lat, lon := HexDecode(c)
+	return HexEncode(lat, lon, c.Resolution-1)
+}
+
+// hexDirections are the axial offsets of the six neighbours of a cell
+var hexDirections = [6][2]int64{{1, 0}, {1, -1}, {0, -1}, {-1, 0}, {-1, 1}, {0, 1}}
+
+// Neighbors returns the six cells sharing an edge with the cell. Unlike geohash cells, all of
+// them have their centres at the same distance.
+func (c HexCell) Neighbors() []HexCell {
+	neighbors := make([]HexCell, 0, 6)
+	for _, d := range hexDirections {
+		neighbors = append(neighbors, HexCell{Resolution: c.Resolution, Zone: c.Zone, Q: c.Q + d[0], R: c.R + d[1]})
+	}
+	return neighbors
+}
+
+// HexKRing returns the cell and every cell within k steps of it, 1 + 3k(k+1) cells in all.
+func HexKRing(c HexCell, k int) []HexCell {
+	cells := make([]HexCell, 0, 1+3*k*(k+1))
+	for dq := -int64(k); dq <= int64(k); dq++ {
+		minDr := max(-int64(k), -dq-int64(k))
+		maxDr := min(int64(k), -dq+int64(k))
+		for dr := minDr; dr <= maxDr; dr++ {
+			cells = append(cells, HexCell{Resolution: c.Resolution, Zone: c.Zone, Q: c.Q + dq, R: c.R + dr})
+		}
+	}
+	return cells
+}
+
+// hexZone returns the longitude zone of a longitude
+func hexZone(lon float64) int {
+	zone := int(math.Floor((normalizeLon(lon) + 180) / hexZoneWidth))
+	if zone >= hexZones {
+		zone = hexZones - 1
+	}
+	return zone
+}
+
+// hexProject projects a location onto the sinusoidal plane of a zone, in meters
+func hexProject(lat, lon float64, zone int) (x, y float64) {
+	central := -180 + hexZoneWidth*(float64(zone)+0.5)
+	phi := lat * math.Pi / 180
+	dLambda := normalizeLon(lon-central) * math.Pi / 180
+	radius := EarthRadiusKm * 1000
+	return radius * dLambda * math.Cos(phi), radius * phi
+}
+
+// hexUnproject inverts hexProject
+func hexUnproject(x, y float64, zone int) (lat, lon float64) {
+	central := -180 + hexZoneWidth*(float64(zone)+0.5)
+	radius := EarthRadiusKm * 1000
+	phi := math.Max(-math.Pi/2, math.Min(math.Pi/2, y/radius))
+	lat = phi * 180 / math.Pi
+	if cosPhi := math.Cos(phi); cosPhi > 1e-12 {
+		lon = normalizeLon(central + x/(radius*cosPhi)*180/math.Pi)
+	} else {
+		lon = central
+	}
+	return lat, lon
+}
+
+// hexRound returns the axial coordinates of the hexagon of the given size containing (x, y)
+func hexRound(x, y, size float64) (int64, int64) {
+	q := (math.Sqrt(3)/3*x - y/3) / size
+	r := (2.0 / 3 * y) / size
+	s := -q - r
+
+	rq, rr, rs := math.Round(q), math.Round(r), math.Round(s)
+	dq, dr, ds := math.Abs(rq-q), math.Abs(rr-r), math.Abs(rs-s)
+	if dq > dr && dq > ds {
+		rq = -rr - rs
+	} else if dr > ds {
+		rr = -rq - rs
+	}
+	return int64(rq), int64(rr)
+}
+
+// normalizeLon wraps a longitude into [-180, 180)
+func normalizeLon(lon float64) float64 {
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	return lon - 180
+}
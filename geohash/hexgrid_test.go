@@ -0,0 +1,149 @@
+package geohash
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomHexLocation returns a random location, half of them within a few cells of a zone
+// boundary where the hexagons of neighbouring zones overlap.
+func randomHexLocation(rng *rand.Rand, resolution int) (float64, float64) {
+	lat := math.Asin(rng.Float64()*2-1) * 180 / math.Pi
+	if rng.Intn(2) == 0 {
+		return lat, rng.Float64()*360 - 180
+	}
+	boundary := -180 + float64(rng.Intn(hexZones))*hexZoneWidth
+	spread := 3 * HexEdgeMeters(resolution) / (EarthRadiusKm * 1000 * math.Max(math.Cos(lat*math.Pi/180), 1e-6)) * 180 / math.Pi
+	return lat, normalizeLon(boundary + (rng.Float64()-0.5)*math.Min(spread, hexZoneWidth))
+}
+
+func TestHexEncodeRoundTrip(t *testing.T) {
+	// Found by the property test below before cells were assigned to the zone of their centre
+	cell := HexEncode(-59.255422, 96.007556, 10)
+	if lat, lon := HexDecode(cell); HexEncode(lat, lon, 10) != cell {
+		t.Errorf("%v does not round trip", cell)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for resolution := 0; resolution <= MaxHexResolution; resolution++ {
+		for i := 0; i < 2000; i++ {
+			lat, lon := randomHexLocation(rng, resolution)
+			cell := HexEncode(lat, lon, resolution)
+			centerLat, centerLon := HexDecode(cell)
+			if again := HexEncode(centerLat, centerLon, resolution); again != cell {
+				t.Fatalf("(%v, %v) at resolution %d: %v, whose centre encodes to %v", lat, lon, resolution, cell, again)
+			}
+			if zone := hexZone(centerLon); zone != cell.Zone {
+				t.Fatalf("(%v, %v) at resolution %d: %v has its centre in zone %d", lat, lon, resolution, cell, zone)
+			}
+			if edges := distanceMeters(lat, lon, centerLat, centerLon) / HexEdgeMeters(resolution); edges > 2 && math.Abs(lat) < 85 {
+				t.Errorf("(%v, %v) at resolution %d: %v has its centre %.2f edges away", lat, lon, resolution, cell, edges)
+			}
+			parsed, err := ParseHexCell(cell.String())
+			if err != nil || parsed != cell {
+				t.Fatalf("ParseHexCell(%q) = %v, %v", cell.String(), parsed, err)
+			}
+		}
+	}
+}
+
+func TestHexEncodeParentContainsCentre(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for resolution := 1; resolution <= MaxHexResolution; resolution++ {
+		for i := 0; i < 200; i++ {
+			lat, lon := randomHexLocation(rng, resolution)
+			cell := HexEncode(lat, lon, resolution)
+			parent := cell.Parent()
+			if parent.Resolution != resolution-1 {
+				t.Fatalf("%v has parent %v", cell, parent)
+			}
+			if parentLat, parentLon := HexDecode(parent); HexEncode(parentLat, parentLon, parent.Resolution) != parent {
+				t.Fatalf("parent %v of %v does not round trip", parent, cell)
+			}
+		}
+	}
+}
+
+// hexDistance returns the number of steps between two cells of the same zone.
+func hexDistance(a, b HexCell) int64 {
+	dq, dr := a.Q-b.Q, a.R-b.R
+	return (abs64(dq) + abs64(dr) + abs64(dq+dr)) / 2
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestHexKRing(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		center := HexCell{Resolution: rng.Intn(MaxHexResolution + 1), Zone: rng.Intn(hexZones), Q: rng.Int63n(2000) - 1000, R: rng.Int63n(2000) - 1000}
+		for k := 0; k <= 6; k++ {
+			ring := HexKRing(center, k)
+			if want := 1 + 3*k*(k+1); len(ring) != want {
+				t.Fatalf("HexKRing(%v, %d) has %d cells, want %d", center, k, len(ring), want)
+			}
+			seen := make(map[HexCell]bool, len(ring))
+			for _, cell := range ring {
+				if seen[cell] {
+					t.Fatalf("HexKRing(%v, %d) repeats %v", center, k, cell)
+				}
+				seen[cell] = true
+				if cell.Resolution != center.Resolution || cell.Zone != center.Zone {
+					t.Fatalf("HexKRing(%v, %d) contains %v", center, k, cell)
+				}
+				if d := hexDistance(center, cell); d > int64(k) {
+					t.Fatalf("HexKRing(%v, %d) contains %v, %d steps away", center, k, cell, d)
+				}
+			}
+			if !seen[center] {
+				t.Fatalf("HexKRing(%v, %d) misses the centre", center, k)
+			}
+			if k == 1 {
+				for _, neighbor := range center.Neighbors() {
+					if !seen[neighbor] || hexDistance(center, neighbor) != 1 {
+						t.Fatalf("neighbour %v of %v is not one step away in the 1-ring", neighbor, center)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestHexNeighborsAreEquidistant(t *testing.T) {
+	center := HexEncode(52.52, 13.405, 12)
+	lat, lon := HexDecode(center)
+	x0, y0 := hexProject(lat, lon, center.Zone)
+	want := math.Sqrt(3) * HexEdgeMeters(center.Resolution)
+	for _, neighbor := range center.Neighbors() {
+		nLat, nLon := HexDecode(neighbor)
+		x, y := hexProject(nLat, nLon, center.Zone)
+		if got := math.Hypot(x-x0, y-y0); math.Abs(got-want) > 1e-6*want {
+			t.Errorf("neighbour %v is %v m away in the zone's projection, want %v", neighbor, got, want)
+		}
+	}
+}
+
+func TestHexGridIndexAcrossZoneBoundaries(t *testing.T) {
+	for _, resolution := range []int{8, 10, 12} {
+		rng := rand.New(rand.NewSource(int64(resolution)))
+		index := NewHexGridIndex(resolution)
+		points := make(map[int64]DriverPoint)
+		for id := int64(1); id <= 400; id++ {
+			lat := -60 + rng.Float64()*0.5
+			lon := 96 + (rng.Float64()-0.5)*0.2
+			points[id] = DriverPoint{ID: id, Lat: lat, Lon: lon}
+			index.Insert(id, lat, lon)
+		}
+		for q := 0; q < 50; q++ {
+			lat, lon := -60+rng.Float64()*0.5, 96+(rng.Float64()-0.5)*0.2
+			for _, meters := range []float64{200, 1000, 5000} {
+				checkSameIDs(t, "hexgrid WithinRadius", index.WithinRadius(lat, lon, meters), bruteForceWithinRadius(points, lat, lon, meters))
+			}
+		}
+	}
+}
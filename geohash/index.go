@@ -0,0 +1,110 @@
+package geohash
+
+import (
+	"math"
+	"sort"
+)
+
+// SpatialIndex is an in-memory index of moving points, such as drivers, keyed by ID.
+// Every geo-indexing technique implements it so they can be swapped and compared.
+type SpatialIndex interface {
+	// Insert adds a point; inserting an ID that is already indexed moves it.
+	Insert(id int64, lat, lon float64)
+	// Update moves a point, inserting it if it is not indexed yet.
+	Update(id int64, lat, lon float64)
+	// Remove deletes a point; removing an unknown ID is a no-op.
+	Remove(id int64)
+	// WithinRadius returns the points within meters of a location, closest first.
+	WithinRadius(lat, lon, meters float64) []DriverPoint
+	// Nearest returns up to k points closest to a location, closest first.
+	Nearest(lat, lon float64, k int) []DriverPoint
+}
+
+// DriverPoint is an indexed point returned by a search.
+type DriverPoint struct {
+	ID             int64   `json:"id"`
+	Lat            float64 `json:"latitude"`
+	Lon            float64 `json:"longitude"`
+	DistanceMeters float64 `json:"distance_meters"`
+}
+
+// maxSearchMeters is half the Earth's circumference, beyond which no point can be
+const maxSearchMeters = math.Pi * EarthRadiusKm * 1000
+
+// nearestByRadius answers a k-nearest query with radius searches, doubling the radius until
+// k points are found or the whole globe is covered
+func nearestByRadius(index SpatialIndex, lat, lon float64, k int) []DriverPoint {
+	if k <= 0 {
+		return nil
+	}
+	for meters := 500.0; ; meters *= 2 {
+		if meters > maxSearchMeters {
+			meters = maxSearchMeters
+		}
+		results := index.WithinRadius(lat, lon, meters)
+		if len(results) >= k || meters == maxSearchMeters {
+			if len(results) > k {
+				results = results[:k]
+			}
+			return results
+		}
+	}
+}
+
+// distanceMeters returns the great-circle distance between two points in meters
+func distanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	return Haversine(lat1, lon1, lat2, lon2) * 1000
+}
+
+// radiusDegrees converts a radius in meters around a latitude to the degrees of latitude and
+// longitude it spans. The longitude span is taken where the circle is widest, which is
+// poleward of its centre; it is 360 when the circle covers a pole.
+func radiusDegrees(lat, meters float64) (latDeg, lonDeg float64) {
+	angular := meters / (EarthRadiusKm * 1000)
+	latDeg = angular * 180 / math.Pi
+	if lat+latDeg >= 90 || lat-latDeg <= -90 {
+		return latDeg, 360
+	}
+	ratio := math.Sin(angular) / math.Cos(lat*math.Pi/180)
+	if ratio >= 1 {
+		return latDeg, 360
+	}
+	return latDeg, math.Asin(ratio) * 180 / math.Pi
+}
+
+// searchBoxes returns the latitude/longitude boxes (X longitude, Y latitude) that together
+// cover every point within meters of a location. A circle crossing the antimeridian is split
+// into a box on each side, and one covering a pole spans every longitude.
+func searchBoxes(lat, lon, meters float64) []Bounds {
+	latDeg, lonDeg := radiusDegrees(lat, meters)
+	minLat := math.Max(lat-latDeg, -90)
+	maxLat := math.Min(lat+latDeg, 90)
+	if lonDeg >= 180 {
+		return []Bounds{{MinX: -180, MinY: minLat, MaxX: 180, MaxY: maxLat}}
+	}
+
+	minLon, maxLon := lon-lonDeg, lon+lonDeg
+	switch {
+	case minLon < -180:
+		return []Bounds{
+			{MinX: -180, MinY: minLat, MaxX: maxLon, MaxY: maxLat},
+			{MinX: minLon + 360, MinY: minLat, MaxX: 180, MaxY: maxLat},
+		}
+	case maxLon > 180:
+		return []Bounds{
+			{MinX: minLon, MinY: minLat, MaxX: 180, MaxY: maxLat},
+			{MinX: -180, MinY: minLat, MaxX: maxLon - 360, MaxY: maxLat},
+		}
+	}
+	return []Bounds{{MinX: minLon, MinY: minLat, MaxX: maxLon, MaxY: maxLat}}
+}
+
+// sortByDistance orders points closest first, breaking ties by ID
+func sortByDistance(points []DriverPoint) {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].DistanceMeters != points[j].DistanceMeters {
+			return points[i].DistanceMeters < points[j].DistanceMeters
+		}
+		return points[i].ID < points[j].ID
+	})
+}
@@ -0,0 +1,338 @@
+package geohash
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// spatialIndexes returns a fresh, empty index of every technique.
+func spatialIndexes() map[string]SpatialIndex {
+	return map[string]SpatialIndex{
+		"geohash":  NewGeohashIndex(driverCellPrecision),
+		"rtree":    NewRTreeIndex(),
+		"quadtree": NewQuadtreeIndex(Bounds{MinX: -180, MinY: -90, MaxX: 180, MaxY: 90}),
+		"hexgrid":  NewHexGridIndex(driverHexResolution),
+	}
+}
+
+// bruteForceWithinRadius returns the points within meters of a location, closest first, by
+// measuring the distance to every point.
+func bruteForceWithinRadius(points map[int64]DriverPoint, lat, lon, meters float64) []DriverPoint {
+	var results []DriverPoint
+	for _, point := range points {
+		point.DistanceMeters = distanceMeters(lat, lon, point.Lat, point.Lon)
+		if point.DistanceMeters <= meters {
+			results = append(results, point)
+		}
+	}
+	sortByDistance(results)
+	return results
+}
+
+// pointIDs lists the IDs of points in order.
+func pointIDs(points []DriverPoint) []int64 {
+	ids := make([]int64, len(points))
+	for i, point := range points {
+		ids[i] = point.ID
+	}
+	return ids
+}
+
+// checkSameIDs fails the test unless got and want hold the same IDs in the same order.
+func checkSameIDs(t *testing.T, what string, got, want []DriverPoint) {
+	t.Helper()
+	if fmt.Sprint(pointIDs(got)) != fmt.Sprint(pointIDs(want)) {
+		t.Errorf("%s: got %v, want %v", what, pointIDs(got), pointIDs(want))
+	}
+}
+
+// randomPoints scatters n points uniformly in a box of the given size around a location.
+func randomPoints(rng *rand.Rand, n int, lat, lon, latSpan, lonSpan float64) map[int64]DriverPoint {
+	points := make(map[int64]DriverPoint, n)
+	for i := 1; i <= n; i++ {
+		pointLon := lon + (rng.Float64()-0.5)*lonSpan
+		if pointLon >= 180 {
+			pointLon -= 360
+		} else if pointLon < -180 {
+			pointLon += 360
+		}
+		pointLat := lat + (rng.Float64()-0.5)*latSpan
+		if pointLat > 90 {
+			pointLat = 180 - pointLat
+		} else if pointLat < -90 {
+			pointLat = -180 - pointLat
+		}
+		points[int64(i)] = DriverPoint{ID: int64(i), Lat: pointLat, Lon: pointLon}
+	}
+	return points
+}
+
+func TestSpatialIndexConformance(t *testing.T) {
+	type step struct {
+		op       string // insert, update or remove
+		id       int64
+		lat, lon float64
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		want  map[int64]DriverPoint // points indexed after the steps
+	}{
+		{
+			name:  "insert",
+			steps: []step{{"insert", 1, 52.52, 13.405}, {"insert", 2, 52.521, 13.406}},
+			want:  map[int64]DriverPoint{1: {ID: 1, Lat: 52.52, Lon: 13.405}, 2: {ID: 2, Lat: 52.521, Lon: 13.406}},
+		},
+		{
+			name:  "insert twice moves",
+			steps: []step{{"insert", 1, 52.52, 13.405}, {"insert", 1, 52.53, 13.42}},
+			want:  map[int64]DriverPoint{1: {ID: 1, Lat: 52.53, Lon: 13.42}},
+		},
+		{
+			name:  "update moves",
+			steps: []step{{"insert", 1, 52.52, 13.405}, {"update", 1, 52.5, 13.38}},
+			want:  map[int64]DriverPoint{1: {ID: 1, Lat: 52.5, Lon: 13.38}},
+		},
+		{
+			name:  "update inserts",
+			steps: []step{{"update", 1, 52.52, 13.405}},
+			want:  map[int64]DriverPoint{1: {ID: 1, Lat: 52.52, Lon: 13.405}},
+		},
+		{
+			name:  "remove",
+			steps: []step{{"insert", 1, 52.52, 13.405}, {"insert", 2, 52.521, 13.406}, {"remove", 1, 0, 0}},
+			want:  map[int64]DriverPoint{2: {ID: 2, Lat: 52.521, Lon: 13.406}},
+		},
+		{
+			name:  "remove unknown",
+			steps: []step{{"insert", 1, 52.52, 13.405}, {"remove", 7, 0, 0}},
+			want:  map[int64]DriverPoint{1: {ID: 1, Lat: 52.52, Lon: 13.405}},
+		},
+		{
+			name:  "far apart",
+			steps: []step{{"insert", 1, 52.52, 13.405}, {"insert", 2, -33.87, 151.21}, {"insert", 3, 40.71, -74.01}},
+			want: map[int64]DriverPoint{
+				1: {ID: 1, Lat: 52.52, Lon: 13.405}, 2: {ID: 2, Lat: -33.87, Lon: 151.21}, 3: {ID: 3, Lat: 40.71, Lon: -74.01},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for name, index := range spatialIndexes() {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				for _, s := range tt.steps {
+					switch s.op {
+					case "insert":
+						index.Insert(s.id, s.lat, s.lon)
+					case "update":
+						index.Update(s.id, s.lat, s.lon)
+					case "remove":
+						index.Remove(s.id)
+					}
+				}
+				for _, query := range []DriverPoint{{Lat: 52.52, Lon: 13.405}, {Lat: -33.87, Lon: 151.21}} {
+					for _, meters := range []float64{100, 5000, maxSearchMeters} {
+						checkSameIDs(t, fmt.Sprintf("WithinRadius(%v, %v, %v)", query.Lat, query.Lon, meters),
+							index.WithinRadius(query.Lat, query.Lon, meters),
+							bruteForceWithinRadius(tt.want, query.Lat, query.Lon, meters))
+					}
+					all := bruteForceWithinRadius(tt.want, query.Lat, query.Lon, maxSearchMeters)
+					checkSameIDs(t, fmt.Sprintf("Nearest(%v, %v, 1)", query.Lat, query.Lon),
+						index.Nearest(query.Lat, query.Lon, 1), all[:min(1, len(all))])
+				}
+			})
+		}
+	}
+}
+
+func TestSpatialIndexesAgreeWithBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	points := randomPoints(rng, 500, 52.52, 13.405, 0.2, 0.3)
+
+	for name, index := range spatialIndexes() {
+		t.Run(name, func(t *testing.T) {
+			for _, point := range points {
+				index.Insert(point.ID, point.Lat, point.Lon)
+			}
+			// Move some points and remove others so every operation is exercised
+			live := make(map[int64]DriverPoint, len(points))
+			for id, point := range points {
+				live[id] = point
+			}
+			ids := make([]int64, 0, len(points))
+			for id := range points {
+				ids = append(ids, id)
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			for _, id := range ids[:100] {
+				moved := DriverPoint{ID: id, Lat: 52.52 + (rng.Float64()-0.5)*0.2, Lon: 13.405 + (rng.Float64()-0.5)*0.3}
+				index.Update(id, moved.Lat, moved.Lon)
+				live[id] = moved
+			}
+			for _, id := range ids[100:150] {
+				index.Remove(id)
+				delete(live, id)
+			}
+
+			for q := 0; q < 50; q++ {
+				lat, lon := 52.52+(rng.Float64()-0.5)*0.2, 13.405+(rng.Float64()-0.5)*0.3
+				for _, meters := range []float64{50, 500, 2000, 10000} {
+					checkSameIDs(t, fmt.Sprintf("WithinRadius(%v, %v, %v)", lat, lon, meters),
+						index.WithinRadius(lat, lon, meters), bruteForceWithinRadius(live, lat, lon, meters))
+				}
+				for _, k := range []int{1, 5, 20} {
+					all := bruteForceWithinRadius(live, lat, lon, maxSearchMeters)
+					checkSameIDs(t, fmt.Sprintf("Nearest(%v, %v, %d)", lat, lon, k), index.Nearest(lat, lon, k), all[:k])
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGeohashIndexWithinRadius(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	index := NewGeohashIndex(driverCellPrecision)
+	for _, point := range randomPoints(rng, 100000, 52.52, 13.405, 2, 3) {
+		index.Insert(point.ID, point.Lat, point.Lon)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.WithinRadius(52.52, 13.405, 2000)
+	}
+}
+
+func TestRadiusDegrees(t *testing.T) {
+	tests := []struct {
+		name           string
+		lat, meters    float64
+		latDeg, lonDeg float64 // expected spans, 0 to only check against the brute force bound
+	}{
+		{"equator", 0, 111195, 1, 1},
+		{"covers north pole", 89.5, 100000, 0, 360},
+		{"covers south pole", -89.5, 100000, 0, 360},
+		{"near pole", 85.5, 10000, 0, 0},
+		{"wider than a hemisphere", 45, 9000000, 0, 360},
+	}
+	for _, tt := range tests {
+		latDeg, lonDeg := radiusDegrees(tt.lat, tt.meters)
+		if tt.latDeg != 0 && !approxEqual(latDeg, tt.latDeg, 1e-3) {
+			t.Errorf("%s: latDeg = %v, want %v", tt.name, latDeg, tt.latDeg)
+		}
+		if tt.lonDeg != 0 && !approxEqual(lonDeg, tt.lonDeg, 1e-3) {
+			t.Errorf("%s: lonDeg = %v, want %v", tt.name, lonDeg, tt.lonDeg)
+		}
+
+		// Every point on the circle must lie within the spans
+		for bearing := 0.0; bearing < 360; bearing += 1 {
+			pLat, pLon := destination(tt.lat, 0, bearing, tt.meters)
+			if dLat := math.Abs(pLat - tt.lat); dLat > latDeg+1e-9 {
+				t.Errorf("%s: bearing %v is %v degrees of latitude away, span %v", tt.name, bearing, dLat, latDeg)
+			}
+			if dLon := math.Abs(pLon); dLon > lonDeg+1e-9 {
+				t.Errorf("%s: bearing %v is %v degrees of longitude away, span %v", tt.name, bearing, dLon, lonDeg)
+			}
+		}
+	}
+}
+
+func TestSearchBoxesCoverTheCircle(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		meters   float64
+		boxes    int
+	}{
+		{"berlin", 52.52, 13.405, 5000, 1},
+		{"east of the antimeridian", 0, -179.99, 5000, 2},
+		{"west of the antimeridian", -16.5, 179.99, 5000, 2},
+		{"on the antimeridian", 65, 180, 20000, 2},
+		{"near the north pole", 86, 45, 50000, 1},
+		{"over the north pole", 89.9, 120, 50000, 1},
+		{"over the south pole", -89.9, -60, 50000, 1},
+	}
+	for _, tt := range tests {
+		boxes := searchBoxes(tt.lat, tt.lon, tt.meters)
+		if len(boxes) != tt.boxes {
+			t.Errorf("%s: %d boxes, want %d: %+v", tt.name, len(boxes), tt.boxes, boxes)
+		}
+		for _, box := range boxes {
+			if box.MinX < -180 || box.MaxX > 180 || box.MinY < -90 || box.MaxY > 90 {
+				t.Errorf("%s: box %+v leaves the globe", tt.name, box)
+			}
+		}
+		for bearing := 0.0; bearing < 360; bearing += 1 {
+			for _, fraction := range []float64{0.5, 0.999} {
+				pLat, pLon := destination(tt.lat, tt.lon, bearing, tt.meters*fraction)
+				if !inAnyBox(boxes, pLat, pLon) {
+					t.Errorf("%s: (%v, %v) at bearing %v is outside %+v", tt.name, pLat, pLon, bearing, boxes)
+				}
+			}
+		}
+	}
+}
+
+func TestSpatialIndexesAcrossAntimeridianAndPoles(t *testing.T) {
+	areas := []struct {
+		name             string
+		lat, lon         float64
+		latSpan, lonSpan float64
+		radii            []float64
+	}{
+		{"antimeridian", 10, 180, 0.4, 0.6, []float64{1000, 10000, 30000}},
+		{"antimeridian far north", 70, -180, 0.4, 1.5, []float64{1000, 10000, 30000}},
+		{"north pole", 89.7, 0, 0.6, 360, []float64{1000, 20000, 60000}},
+		{"above 85", 86.5, 100, 1, 20, []float64{1000, 20000, 60000}},
+		{"south pole", -89.8, 0, 0.4, 360, []float64{1000, 20000, 60000}},
+	}
+
+	for _, area := range areas {
+		rng := rand.New(rand.NewSource(1))
+		points := randomPoints(rng, 400, area.lat, area.lon, area.latSpan, area.lonSpan)
+		for name, index := range spatialIndexes() {
+			t.Run(area.name+"/"+name, func(t *testing.T) {
+				for _, point := range points {
+					index.Insert(point.ID, point.Lat, point.Lon)
+				}
+				queries := randomPoints(rng, 20, area.lat, area.lon, area.latSpan, area.lonSpan)
+				for _, query := range queries {
+					for _, meters := range area.radii {
+						checkSameIDs(t, fmt.Sprintf("WithinRadius(%v, %v, %v)", query.Lat, query.Lon, meters),
+							index.WithinRadius(query.Lat, query.Lon, meters),
+							bruteForceWithinRadius(points, query.Lat, query.Lon, meters))
+					}
+					all := bruteForceWithinRadius(points, query.Lat, query.Lon, maxSearchMeters)
+					checkSameIDs(t, fmt.Sprintf("Nearest(%v, %v, 5)", query.Lat, query.Lon),
+						index.Nearest(query.Lat, query.Lon, 5), all[:5])
+				}
+			})
+		}
+	}
+}
+
+// destination returns the point meters away from a location along a bearing in degrees,
+// with longitude normalised to [-180, 180).
+func destination(lat, lon, bearing, meters float64) (float64, float64) {
+	angular := meters / (EarthRadiusKm * 1000)
+	φ1, λ1, θ := lat*math.Pi/180, lon*math.Pi/180, bearing*math.Pi/180
+	φ2 := math.Asin(math.Sin(φ1)*math.Cos(angular) + math.Cos(φ1)*math.Sin(angular)*math.Cos(θ))
+	λ2 := λ1 + math.Atan2(math.Sin(θ)*math.Sin(angular)*math.Cos(φ1), math.Cos(angular)-math.Sin(φ1)*math.Sin(φ2))
+	lon2 := math.Mod(λ2*180/math.Pi+540, 360) - 180
+	return φ2 * 180 / math.Pi, lon2
+}
+
+// inAnyBox reports whether a point lies in one of boxes.
+func inAnyBox(boxes []Bounds, lat, lon float64) bool {
+	for _, box := range boxes {
+		if lat >= box.MinY-1e-9 && lat <= box.MaxY+1e-9 && lon >= box.MinX-1e-9 && lon <= box.MaxX+1e-9 {
+			return true
+		}
+	}
+	return false
+}
+
+func approxEqual(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
@@ -0,0 +1,74 @@
+package geohash
+
+import (
+	"math"
+	"rider-assignment-system/config"
+	"sort"
+	"strings"
+)
+
+// DefaultPrecision is the geohash precision used when none is configured (cells of ~4.9 km).
+const DefaultPrecision = 5
+
+// PrecisionAt returns the geohash precision stored for a location: geohash.precision from
+// config, unless a geohash.zones entry covers the location. Zones are keyed by geohash
+// prefix, and the longest matching prefix wins, so a dense city centre can use finer cells
+// than its surroundings.
+func PrecisionAt(lat, lon float64) uint {
+	precision := uint(config.GetInt("geohash.precision", DefaultPrecision))
+
+	hash := Encode(lat, lon, 12)
+	matched := 0
+	for prefix, zonePrecision := range config.GetIntMap("geohash.zones") {
+		if len(prefix) > matched && strings.HasPrefix(hash, strings.ToLower(prefix)) {
+			precision = uint(zonePrecision)
+			matched = len(prefix)
+		}
+	}
+	return clampPrecision(precision)
+}
+
+// EncodeAt encodes a location at the precision configured for it.
+func EncodeAt(lat, lon float64) string {
+	return Encode(lat, lon, PrecisionAt(lat, lon))
+}
+
+// IndexPrecisions returns the precisions available drivers are indexed at, finest first.
+// Matching searches them in this order, coarsening until drivers are found.
+func IndexPrecisions() []uint {
+	seen := make(map[uint]bool)
+	var precisions []uint
+	for _, p := range config.GetInts("geohash.index_precisions", []int{7, 6, 5}) {
+		if p < 1 {
+			continue
+		}
+		precision := clampPrecision(uint(p))
+		if !seen[precision] {
+			seen[precision] = true
+			precisions = append(precisions, precision)
+		}
+	}
+	sort.Slice(precisions, func(i, j int) bool { return precisions[i] > precisions[j] })
+	return precisions
+}
+
+// CellSizeKm returns the smaller side of a geohash cell of the given precision at a latitude,
+// which is the radius a search of the cell and its neighbours is guaranteed to cover.
+func CellSizeKm(lat float64, precision uint) float64 {
+	latBits := (5 * precision) / 2
+	lonBits := 5*precision - latBits
+	height := 180 / math.Pow(2, float64(latBits)) * math.Pi / 180 * EarthRadiusKm
+	width := 360 / math.Pow(2, float64(lonBits)) * math.Pi / 180 * EarthRadiusKm * math.Cos(lat*math.Pi/180)
+	return math.Min(height, width)
+}
+
+// clampPrecision keeps a precision within what a geohash can encode
+func clampPrecision(precision uint) uint {
+	if precision < 1 {
+		return 1
+	}
+	if precision > 12 {
+		return 12
+	}
+	return precision
+}
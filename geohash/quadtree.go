@@ -1,13 +1,20 @@
 package geohash
 
 import (
+	"container/heap"
 	"math"
 	"sync"
 )
 
-// Point represents a point in 2D space
+const (
+	quadtreeNodeCapacity = 4  // points a leaf holds before it splits
+	quadtreeMaxDepth     = 32 // leaves this deep never split, so coincident points cannot recurse forever
+)
+
+// Point represents a point in 2D space; X is longitude and Y latitude for geographic points
 type Point struct {
 	X, Y float64
+	ID   int64 // driver ID, zero for anonymous points
 }
 
 // Bounds represents the boundaries of a region
@@ -35,28 +42,90 @@ func InitializeQuadtree(bounds Bounds) *Quadtree {
 	}
 }
 
-// Insert adds a point to the Quadtree
+// Insert adds a point to the Quadtree. Points outside the root bounds are ignored.
 func (qt *Quadtree) Insert(point Point) {
 	qt.Lock.Lock()
 	defer qt.Lock.Unlock()
-	qt.Root.insert(point)
+	qt.Root.insert(point, 0)
 }
 
-// insert adds a point to a QuadtreeNode, creating children nodes if necessary
-func (node *QuadtreeNode) insert(point Point) {
+// insert adds a point to the one leaf covering it, splitting the leaf once it is full
+func (node *QuadtreeNode) insert(point Point, depth int) {
 	if !node.contains(point) {
 		return
 	}
-	if len(node.Points) < 4 && node.Children[0] == nil {
-		node.Points = append(node.Points, point)
+	if node.Children[0] != nil {
+		node.Children[node.childIndex(point)].insert(point, depth+1)
 		return
 	}
-	if node.Children[0] == nil {
+	node.Points = append(node.Points, point)
+	if len(node.Points) > quadtreeNodeCapacity && depth < quadtreeMaxDepth {
 		node.subdivide()
+		points := node.Points
+		node.Points = nil
+		for _, p := range points {
+			node.Children[node.childIndex(p)].insert(p, depth+1)
+		}
+	}
+}
+
+// Remove deletes the point with the given ID at the given position from the Quadtree,
+// merging nodes that become sparse. It reports whether the point was found.
+func (qt *Quadtree) Remove(point Point) bool {
+	qt.Lock.Lock()
+	defer qt.Lock.Unlock()
+	return qt.Root.remove(point)
+}
+
+// remove deletes the point from the leaf covering its position
+func (node *QuadtreeNode) remove(point Point) bool {
+	if !node.contains(point) {
+		return false
+	}
+	if node.Children[0] != nil {
+		removed := node.Children[node.childIndex(point)].remove(point)
+		if removed {
+			node.merge()
+		}
+		return removed
+	}
+	for i, p := range node.Points {
+		if p.ID == point.ID {
+			node.Points = append(node.Points[:i], node.Points[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Move relocates a point from one position to another, inserting it at the new position
+// even if it was not found at the old one
+func (qt *Quadtree) Move(from, to Point) {
+	qt.Lock.Lock()
+	defer qt.Lock.Unlock()
+	qt.Root.remove(from)
+	qt.Root.insert(to, 0)
+}
+
+// merge folds the children back into the node once they are all leaves holding no more
+// points than a single leaf may
+func (node *QuadtreeNode) merge() {
+	total := 0
+	for _, child := range node.Children {
+		if child.Children[0] != nil {
+			return
+		}
+		total += len(child.Points)
+	}
+	if total > quadtreeNodeCapacity {
+		return
 	}
-	for i := 0; i < 4; i++ {
-		node.Children[i].insert(point)
+	var points []Point
+	for _, child := range node.Children {
+		points = append(points, child.Points...)
 	}
+	node.Points = points
+	node.Children = [4]*QuadtreeNode{}
 }
 
 // contains checks if the point is within the node's bounds
@@ -65,6 +134,21 @@ func (node *QuadtreeNode) contains(point Point) bool {
 		point.Y >= node.Bounds.MinY && point.Y <= node.Bounds.MaxY
 }
 
+// childIndex returns the child quadrant a point belongs to; points on a dividing line go to
+// the upper or right quadrant, so every point has exactly one
+func (node *QuadtreeNode) childIndex(point Point) int {
+	midX := (node.Bounds.MinX + node.Bounds.MaxX) / 2
+	midY := (node.Bounds.MinY + node.Bounds.MaxY) / 2
+	index := 0
+	if point.X >= midX {
+		index |= 1
+	}
+	if point.Y >= midY {
+		index |= 2
+	}
+	return index
+}
+
 // subdivide splits the node into four child nodes
 func (node *QuadtreeNode) subdivide() {
 	midX := (node.Bounds.MinX + node.Bounds.MaxX) / 2
@@ -75,7 +159,75 @@ func (node *QuadtreeNode) subdivide() {
 	node.Children[3] = &QuadtreeNode{Bounds: Bounds{midX, midY, node.Bounds.MaxX, node.Bounds.MaxY}}
 }
 
-// SearchNearbyInQuadtree searches for nearby points within a given radius
+// KNearest returns the k points closest to center, closest first. Nodes are visited best
+// first, in order of their distance from center, so only the nodes that could hold one of
+// the k nearest points are opened.
+func (qt *Quadtree) KNearest(center Point, k int) []Point {
+	qt.Lock.Lock()
+	defer qt.Lock.Unlock()
+	if k <= 0 {
+		return nil
+	}
+
+	queue := &quadtreeQueue{{node: qt.Root, distance: qt.Root.distanceTo(center)}}
+	var result []Point
+	for queue.Len() > 0 && len(result) < k {
+		item := heap.Pop(queue).(quadtreeQueueItem)
+		switch {
+		case item.node == nil:
+			result = append(result, item.point)
+		case item.node.Children[0] != nil:
+			for _, child := range item.node.Children {
+				heap.Push(queue, quadtreeQueueItem{node: child, distance: child.distanceTo(center)})
+			}
+		default:
+			for _, p := range item.node.Points {
+				heap.Push(queue, quadtreeQueueItem{point: p, distance: distance(p, center)})
+			}
+		}
+	}
+	return result
+}
+
+// distanceTo returns the distance from a point to the closest part of the node's bounds
+func (node *QuadtreeNode) distanceTo(center Point) float64 {
+	closestX := math.Max(node.Bounds.MinX, math.Min(center.X, node.Bounds.MaxX))
+	closestY := math.Max(node.Bounds.MinY, math.Min(center.Y, node.Bounds.MaxY))
+	return distance(Point{X: closestX, Y: closestY}, center)
+}
+
+// quadtreeQueueItem is a node still to open or a point found during a KNearest search
+type quadtreeQueueItem struct {
+	node     *QuadtreeNode // nil for a point
+	point    Point
+	distance float64
+}
+
+// quadtreeQueue is a min-heap of KNearest items ordered by distance; points win ties with
+// nodes so they are returned as soon as nothing closer can remain
+type quadtreeQueue []quadtreeQueueItem
+
+func (q quadtreeQueue) Len() int { return len(q) }
+func (q quadtreeQueue) Less(i, j int) bool {
+	if q[i].distance != q[j].distance {
+		return q[i].distance < q[j].distance
+	}
+	if (q[i].node == nil) != (q[j].node == nil) {
+		return q[i].node == nil
+	}
+	return q[i].node == nil && q[i].point.ID < q[j].point.ID
+}
+func (q quadtreeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *quadtreeQueue) Push(x interface{}) { *q = append(*q, x.(quadtreeQueueItem)) }
+func (q *quadtreeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// SearchNearbyInQuadtree searches for nearby points within a given radius, measured in the
+// tree's own units; geographic searches in meters go through QuadtreeIndex.WithinRadius
 func (qt *Quadtree) SearchNearbyInQuadtree(center Point, radius float64) []Point {
 	qt.Lock.Lock()
 	defer qt.Lock.Unlock()
@@ -101,6 +253,32 @@ func (node *QuadtreeNode) searchNearby(center Point, radius float64) []Point {
 	return result
 }
 
+// SearchBox returns the points inside a rectangle, edges included
+func (qt *Quadtree) SearchBox(box Bounds) []Point {
+	qt.Lock.Lock()
+	defer qt.Lock.Unlock()
+	return qt.Root.searchBox(box, nil)
+}
+
+// searchBox appends the points of a QuadtreeNode inside box to result
+func (node *QuadtreeNode) searchBox(box Bounds, result []Point) []Point {
+	if box.MinX > node.Bounds.MaxX || box.MaxX < node.Bounds.MinX ||
+		box.MinY > node.Bounds.MaxY || box.MaxY < node.Bounds.MinY {
+		return result
+	}
+	for _, point := range node.Points {
+		if point.X >= box.MinX && point.X <= box.MaxX && point.Y >= box.MinY && point.Y <= box.MaxY {
+			result = append(result, point)
+		}
+	}
+	if node.Children[0] != nil {
+		for i := 0; i < 4; i++ {
+			result = node.Children[i].searchBox(box, result)
+		}
+	}
+	return result
+}
+
 // intersectsCircle checks if a circle intersects with the node's bounds
 func (node *QuadtreeNode) intersectsCircle(center Point, radius float64) bool {
 	closestX := math.Max(node.Bounds.MinX, math.Min(center.X, node.Bounds.MaxX))
@@ -116,3 +294,80 @@ func distance(a, b Point) float64 {
 	dy := a.Y - b.Y
 	return math.Sqrt(dx*dx + dy*dy)
 }
+
+// QuadtreeIndex indexes points in a quadtree over (longitude, latitude)
+type QuadtreeIndex struct {
+	mu     sync.RWMutex
+	tree   *Quadtree
+	points map[int64]Point
+}
+
+// NewQuadtreeIndex creates an empty quadtree index covering bounds
+func NewQuadtreeIndex(bounds Bounds) *QuadtreeIndex {
+	return &QuadtreeIndex{
+		tree:   InitializeQuadtree(bounds),
+		points: make(map[int64]Point),
+	}
+}
+
+// Insert adds a point, moving it if it is already indexed
+func (idx *QuadtreeIndex) Insert(id int64, lat, lon float64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	point := Point{X: lon, Y: lat, ID: id}
+	if old, ok := idx.points[id]; ok {
+		idx.tree.Move(old, point)
+	} else {
+		idx.tree.Insert(point)
+	}
+	idx.points[id] = point
+}
+
+// Update moves a point, inserting it if needed
+func (idx *QuadtreeIndex) Update(id int64, lat, lon float64) {
+	idx.Insert(id, lat, lon)
+}
+
+// Remove deletes a point
+func (idx *QuadtreeIndex) Remove(id int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if old, ok := idx.points[id]; ok {
+		idx.tree.Remove(old)
+		delete(idx.points, id)
+	}
+}
+
+// WithinRadius returns the points within meters of a location, closest first
+func (idx *QuadtreeIndex) WithinRadius(lat, lon, meters float64) []DriverPoint {
+	var results []DriverPoint
+	for _, box := range searchBoxes(lat, lon, meters) {
+		for _, point := range idx.tree.SearchBox(box) {
+			result := DriverPoint{ID: point.ID, Lat: point.Y, Lon: point.X}
+			result.DistanceMeters = distanceMeters(lat, lon, result.Lat, result.Lon)
+			if result.DistanceMeters <= meters {
+				results = append(results, result)
+			}
+		}
+	}
+	sortByDistance(results)
+	return results
+}
+
+// Nearest returns up to k points closest to a location. The quadtree's k nearest in degrees
+// bound the search: the true k nearest lie no further away than the furthest of them.
+func (idx *QuadtreeIndex) Nearest(lat, lon float64, k int) []DriverPoint {
+	seeds := idx.tree.KNearest(Point{X: lon, Y: lat}, k)
+	if len(seeds) == 0 {
+		return nil
+	}
+	bound := 0.0
+	for _, seed := range seeds {
+		bound = math.Max(bound, distanceMeters(lat, lon, seed.Y, seed.X))
+	}
+	results := idx.WithinRadius(lat, lon, bound)
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
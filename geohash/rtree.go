@@ -2,42 +2,104 @@ package geohash
 
 import (
 	"github.com/dhconnelly/rtreego"
+	"math"
 	"sync"
 )
 
 // SpatialPoint wraps a point to satisfy the rtreego.Spatial interface
 type SpatialPoint struct {
 	rtreego.Point
+	ID int64 // driver ID, zero for anonymous points
 }
 
-// BoundingBox returns a rectangle representing the spatial bounds of the point
-func (p SpatialPoint) BoundingBox() *rtreego.Rect {
+// Bounds returns a rectangle representing the spatial bounds of the point
+func (p SpatialPoint) Bounds() rtreego.Rect {
 	// Create a small bounding box around the point
 	zeroDistance := 0.0001 // A very small distance to represent the bounding box
-	rect := p.Point.ToRect(zeroDistance)
-	return &rect
+	return p.Point.ToRect(zeroDistance)
 }
 
-var rtree *rtreego.Rtree
-var rtreeLock sync.Mutex
+// RTreeIndex indexes points in an R-tree over (latitude, longitude)
+type RTreeIndex struct {
+	mu     sync.RWMutex
+	tree   *rtreego.Rtree
+	points map[int64]*SpatialPoint // the exact object inserted for each ID, which Delete needs to find it
+}
+
+// NewRTreeIndex creates an empty R-tree index
+func NewRTreeIndex() *RTreeIndex {
+	return &RTreeIndex{
+		tree:   rtreego.NewTree(2, 25, 50),
+		points: make(map[int64]*SpatialPoint),
+	}
+}
 
 // InitializeRTree initializes the R-tree for spatial indexing
 func InitializeRTree() {
-	rtree = rtreego.NewTree(2, 25, 50)
-}
-
-//// AddPointToRTree adds a point to the R-tree
-//func AddPointToRTree(lat, lon float64) {
-//	rtreeLock.Lock()
-//	defer rtreeLock.Unlock()
-//	point := SpatialPoint{rtreego.Point{lat, lon}}
-//	rtree.Insert(point)
-//}
-
-// SearchNearbyInRTree searches for nearby points within a given radius
-func SearchNearbyInRTree(lat, lon, radius float64) []rtreego.Spatial {
-	rtreeLock.Lock()
-	defer rtreeLock.Unlock()
-	point := rtreego.Point{lat, lon}
-	return rtree.SearchIntersect(point.ToRect(radius))
+	registerIndex(RTreeTechnique, NewRTreeIndex())
+}
+
+// Insert adds a point, moving it if it is already indexed
+func (idx *RTreeIndex) Insert(id int64, lat, lon float64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(id)
+	point := &SpatialPoint{Point: rtreego.Point{lat, lon}, ID: id}
+	idx.tree.Insert(point)
+	idx.points[id] = point
+}
+
+// Update moves a point, inserting it if needed
+func (idx *RTreeIndex) Update(id int64, lat, lon float64) {
+	idx.Insert(id, lat, lon)
+}
+
+// Remove deletes a point
+func (idx *RTreeIndex) Remove(id int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(id)
+}
+
+// removeLocked deletes a point; idx.mu must be held
+func (idx *RTreeIndex) removeLocked(id int64) {
+	if old, ok := idx.points[id]; ok {
+		idx.tree.Delete(old)
+		delete(idx.points, id)
+	}
+}
+
+// WithinRadius returns the points within meters of a location, closest first
+func (idx *RTreeIndex) WithinRadius(lat, lon, meters float64) []DriverPoint {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var results []DriverPoint
+	for _, box := range searchBoxes(lat, lon, meters) {
+		rect, err := rtreego.NewRect(
+			rtreego.Point{box.MinY, box.MinX},
+			[]float64{math.Max(box.MaxY-box.MinY, 1e-9), math.Max(box.MaxX-box.MinX, 1e-9)},
+		)
+		if err != nil {
+			continue
+		}
+		for _, item := range idx.tree.SearchIntersect(rect) {
+			point, ok := item.(*SpatialPoint)
+			if !ok {
+				continue
+			}
+			result := DriverPoint{ID: point.ID, Lat: point.Point[0], Lon: point.Point[1]}
+			result.DistanceMeters = distanceMeters(lat, lon, result.Lat, result.Lon)
+			if result.DistanceMeters <= meters {
+				results = append(results, result)
+			}
+		}
+	}
+	sortByDistance(results)
+	return results
+}
+
+// Nearest returns up to k points closest to a location
+func (idx *RTreeIndex) Nearest(lat, lon float64, k int) []DriverPoint {
+	return nearestByRadius(idx, lat, lon, k)
 }
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"rider-assignment-system/geofence"
 	"rider-assignment-system/geohash"
 	"time"
 
@@ -11,6 +13,9 @@ import (
 	"rider-assignment-system/cache"
 	"rider-assignment-system/config"
 	"rider-assignment-system/database"
+	"rider-assignment-system/dispatch"
+	"rider-assignment-system/presence"
+	"rider-assignment-system/surge"
 
 	"github.com/gorilla/handlers"
 )
@@ -35,18 +40,44 @@ func main() {
 	}
 
 	// Set the default geo-indexing technique
-	geohash.SetDefaultTechnique(geohash.GeohashingTechnique)
+	geohash.SetDefaultTechnique(geohash.GeoIndexingTechnique(config.GetEnv("geoindex.technique", string(geohash.GeohashingTechnique))))
 
 	// Initialize the R-tree
 	geohash.InitializeRTree()
 
 	// Initialize the Quadtree with specified bounds
 	quadtreeBounds := geohash.Bounds{
-		MinX: -180, MinY: -90, // Minimum bounds for latitude and longitude
-		MaxX: 180, MaxY: 90, // Maximum bounds for latitude and longitude
+		MinX: -180, MinY: -90, // Minimum longitude (X) and latitude (Y)
+		MaxX: 180, MaxY: 90, // Maximum longitude (X) and latitude (Y)
 	}
 	geohash.InitializeGlobalQuadtree(quadtreeBounds)
 
+	// Load the service zones ride requests are checked against and queues are rebuilt from
+	if err := geofence.LoadZones(context.Background()); err != nil {
+		log.Fatalf("Failed to load zones: %v", err)
+	}
+	geofence.StartRefresher(config.GetDuration("geofence.refresh_interval", time.Minute))
+
+	// Repopulate the availability indexes from the database and keep them in sync
+	indexed, err := cache.RebuildAvailability(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to rebuild driver availability: %v", err)
+	}
+	log.Printf("Indexed %d available drivers.", indexed)
+	cache.StartReconciler(config.GetDuration("redis.reconcile_interval", time.Minute))
+
+	// Re-dispatch trips whose offers drivers did not answer in time
+	dispatch.StartOfferSweeper(config.GetDuration("dispatch.sweep_interval", time.Second))
+
+	// Recompute surge multipliers from recent ride requests and available drivers
+	surge.StartEngine(config.GetDuration("surge.interval", time.Minute))
+
+	// Mark drivers offline when their location pings stop
+	presence.StartSweeper(
+		config.GetDuration("drivers.sweep_interval", 15*time.Second),
+		config.GetDuration("drivers.heartbeat_ttl", time.Minute),
+	)
+
 	// Register routes for the API
 	router := api.RegisterRoutes()
 
@@ -0,0 +1,117 @@
+package matching
+
+import "math"
+
+// Unassignable is the cost to use for rider/driver pairs that must not be matched, such as a
+// driver outside the rider's search radius. Pairs at or above this cost are never assigned.
+const Unassignable = 1e9
+
+// SolveAssignment finds the rider-to-driver assignment with the lowest total cost using the
+// Hungarian algorithm. cost[i][j] is the cost of giving driver j to rider i and must be finite.
+// The result holds, for every rider, the index of its driver or -1 when it gets none (more
+// riders than drivers, or only Unassignable pairs left).
+func SolveAssignment(cost [][]float64) []int {
+	n := len(cost)
+	if n == 0 {
+		return nil
+	}
+	m := len(cost[0])
+	if m == 0 {
+		return unassigned(n)
+	}
+
+	// The algorithm below needs at least as many columns as rows
+	if n > m {
+		driverToRider := SolveAssignment(transpose(cost))
+		result := unassigned(n)
+		for j, i := range driverToRider {
+			if i >= 0 {
+				result[i] = j
+			}
+		}
+		return result
+	}
+
+	// Potentials u (rows) and v (columns), p[j] is the row matched to column j, all 1-indexed
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	p := make([]int, m+1)
+	way := make([]int, m+1)
+
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, m+1)
+		used := make([]bool, m+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		for {
+			used[j0] = true
+			i0 := p[j0]
+			delta := math.Inf(1)
+			j1 := 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				cur := cost[i0-1][j-1] - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		// Flip the augmenting path
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	result := unassigned(n)
+	for j := 1; j <= m; j++ {
+		if i := p[j]; i != 0 && cost[i-1][j-1] < Unassignable {
+			result[i-1] = j - 1
+		}
+	}
+	return result
+}
+
+// unassigned returns n rider slots with no driver.
+func unassigned(n int) []int {
+	result := make([]int, n)
+	for i := range result {
+		result[i] = -1
+	}
+	return result
+}
+
+// transpose swaps the rows and columns of a cost matrix.
+func transpose(cost [][]float64) [][]float64 {
+	t := make([][]float64, len(cost[0]))
+	for j := range t {
+		t[j] = make([]float64, len(cost))
+		for i := range cost {
+			t[j][i] = cost[i][j]
+		}
+	}
+	return t
+}
@@ -0,0 +1,89 @@
+package matching
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// bruteForceAssignment returns the lowest total cost of assigning every rider a distinct
+// driver, trying every permutation. It needs at least as many drivers as riders.
+func bruteForceAssignment(cost [][]float64) float64 {
+	best := math.Inf(1)
+	taken := make([]bool, len(cost[0]))
+	var assign func(i int, total float64)
+	assign = func(i int, total float64) {
+		if i == len(cost) {
+			best = math.Min(best, total)
+			return
+		}
+		for j := range taken {
+			if !taken[j] {
+				taken[j] = true
+				assign(i+1, total+cost[i][j])
+				taken[j] = false
+			}
+		}
+	}
+	assign(0, 0)
+	return best
+}
+
+func TestSolveAssignmentMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for run := 0; run < 200; run++ {
+		riders := 1 + rng.Intn(5)
+		drivers := riders + rng.Intn(3)
+		cost := make([][]float64, riders)
+		for i := range cost {
+			cost[i] = make([]float64, drivers)
+			for j := range cost[i] {
+				cost[i][j] = rng.Float64() * 10
+			}
+		}
+
+		solution := SolveAssignment(cost)
+		used := make(map[int]bool)
+		total := 0.0
+		for i, j := range solution {
+			if j < 0 || used[j] {
+				t.Fatalf("run %d: rider %d got driver %d in %v", run, i, j, solution)
+			}
+			used[j] = true
+			total += cost[i][j]
+		}
+		if want := bruteForceAssignment(cost); math.Abs(total-want) > 1e-9 {
+			t.Errorf("run %d: total cost %f, want %f", run, total, want)
+		}
+	}
+}
+
+func TestSolveAssignmentLeavesUnassignablePairsOut(t *testing.T) {
+	cost := [][]float64{
+		{1, Unassignable},
+		{Unassignable, Unassignable},
+		{2, 3},
+	}
+	solution := SolveAssignment(cost)
+	if solution[1] != -1 {
+		t.Errorf("rider with only unassignable drivers got driver %d", solution[1])
+	}
+	if solution[0] != 0 || solution[2] != 1 {
+		t.Errorf("solution = %v, want [0 -1 1]", solution)
+	}
+}
+
+func BenchmarkSolveAssignment(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	cost := make([][]float64, 100)
+	for i := range cost {
+		cost[i] = make([]float64, 120)
+		for j := range cost[i] {
+			cost[i][j] = rng.Float64() * 10
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SolveAssignment(cost)
+	}
+}
@@ -0,0 +1,122 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rider-assignment-system/config"
+	"rider-assignment-system/routing"
+	"time"
+)
+
+// errRoutingBudget is returned when a request has used up its routing lookups
+var errRoutingBudget = errors.New("routing lookup budget exhausted")
+
+// routingBudget caps the routing lookups made while matching one trip request, across every
+// ring of an expanding search. A matrix request counts as one lookup.
+type routingBudget struct {
+	remaining int
+}
+
+func newRoutingBudget() *routingBudget {
+	return &routingBudget{remaining: config.GetInt("matching.eta.max_routing_calls", 10)}
+}
+
+// take uses up one lookup, reporting false when none are left
+func (b *routingBudget) take() bool {
+	if b.remaining <= 0 {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+// budget returns the request's routing budget
+func (req Request) budget() *routingBudget {
+	if req.routing == nil {
+		return newRoutingBudget()
+	}
+	return req.routing
+}
+
+// applyRoadETAs replaces the haversine distances of the matching.eta.shortlist candidates nearest
+// the pickup with road distances and durations, and returns how many it replaced. Candidates
+// must be sorted by distance; the shortlist is the first n of them.
+//
+// The shortlist is routed with one matrix request when the router supports it, and otherwise
+// one lookup per candidate, all within matching.eta.timeout and the request's routing budget.
+// Candidates are left untouched unless every lookup succeeds with the same kind of answer, so
+// the ranking never mixes units or mixes road ETAs with offline estimates.
+func applyRoadETAs(req Request, candidates []Candidate) (int, error) {
+	n := min(len(candidates), config.GetInt("matching.eta.shortlist", 5))
+	if n <= 0 {
+		return 0, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetDuration("matching.eta.timeout", 2*time.Second))
+	defer cancel()
+
+	routes, err := shortlistRoutes(ctx, req, candidates[:n])
+	if err != nil {
+		return 0, err
+	}
+	for _, route := range routes[1:] {
+		if route.Provider != routes[0].Provider {
+			return 0, fmt.Errorf("routes came from both %s and %s", routes[0].Provider, route.Provider)
+		}
+	}
+
+	for i, route := range routes {
+		candidates[i].DistanceKm = route.DistanceKm()
+		candidates[i].ETASeconds = route.DurationSeconds
+		candidates[i].DistanceSource = DistanceSourceRoad
+		if route.Provider == routing.ProviderOffline {
+			candidates[i].DistanceSource = DistanceSourceEstimate
+		}
+	}
+	return n, nil
+}
+
+// shortlistRoutes routes every shortlisted candidate to the pickup
+func shortlistRoutes(ctx context.Context, req Request, shortlist []Candidate) ([]routing.Route, error) {
+	router := routing.Default()
+	budget := req.budget()
+	pickup := routing.Point{Lat: req.PickupLat, Lon: req.PickupLon}
+	sources := make([]routing.Point, len(shortlist))
+	for i, c := range shortlist {
+		sources[i] = routing.Point{Lat: c.Driver.Latitude, Lon: c.Driver.Longitude}
+	}
+
+	if matrixRouter, ok := router.(routing.MatrixRouter); ok && len(shortlist) > 1 && budget.take() {
+		if matrix, err := matrixRouter.Matrix(ctx, sources, []routing.Point{pickup}); err == nil {
+			if routes, err := matrixRoutes(matrix, shortlist); err == nil {
+				return routes, nil
+			}
+		}
+	}
+
+	routes := make([]routing.Route, len(shortlist))
+	for i, source := range sources {
+		if !budget.take() {
+			return nil, errRoutingBudget
+		}
+		route, err := router.Route(ctx, source, pickup)
+		if err != nil {
+			return nil, err
+		}
+		routes[i] = route
+	}
+	return routes, nil
+}
+
+// matrixRoutes reads the routes of a one-column matrix, failing if any candidate has no route
+func matrixRoutes(matrix routing.Matrix, shortlist []Candidate) ([]routing.Route, error) {
+	routes := make([]routing.Route, len(shortlist))
+	for i, c := range shortlist {
+		distance, duration := matrix.DistancesMeters[i][0], matrix.DurationsSeconds[i][0]
+		if distance == nil || duration == nil {
+			return nil, fmt.Errorf("no route from driver %d", c.Driver.ID)
+		}
+		routes[i] = routing.Route{DistanceMeters: *distance, DurationSeconds: *duration, Provider: matrix.Provider}
+	}
+	return routes, nil
+}
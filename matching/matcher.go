@@ -2,32 +2,182 @@ package matching
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
+	"math"
 	"rider-assignment-system/cache"
+	"rider-assignment-system/config"
 	"rider-assignment-system/geohash"
 	"rider-assignment-system/models"
+	"sort"
+	"time"
 )
 
-func FindNearestDriver(riderLat, riderLon float64) (*models.Driver, error) {
-	riderHash := geohash.Encode(riderLat, riderLon, 5)
-	neighbors := geohash.GetNeighbors(riderHash)
-	neighbors = append(neighbors, riderHash)
+const (
+	// DistanceSourceHaversine ranks candidates by great-circle distance.
+	DistanceSourceHaversine = "haversine"
+	// DistanceSourceRoad ranks candidates by road ETA from the routing service.
+	DistanceSourceRoad = "road"
+	// DistanceSourceEstimate ranks candidates by offline road estimates, used when the routing
+	// service cannot be reached.
+	DistanceSourceEstimate = "estimate"
+)
+
+// ErrNoDriverAvailable is returned when no driver near the pickup can be matched.
+var ErrNoDriverAvailable = errors.New("no available drivers nearby")
+
+// Candidate is an available driver together with the measures used to rank it.
+type Candidate struct {
+	Driver         models.Driver `json:"driver"`
+	DistanceKm     float64       `json:"distance_km"`
+	ETASeconds     float64       `json:"eta_seconds,omitempty"`
+	DistanceSource string        `json:"distance_source"`
+	IdleSeconds    float64       `json:"idle_seconds,omitempty"`   // time since the driver last became available
+	SearchRadiusKm float64       `json:"search_radius_km"`         // radius the search had to widen to
+	QueuePosition  int           `json:"queue_position,omitempty"` // place in a FIFO queue the driver was dispatched from
+}
+
+// Request describes the trip a driver is being matched to.
+type Request struct {
+	PickupLat  float64
+	PickupLon  float64
+	DropoffLat float64
+	DropoffLon float64
 
-	ctx := context.Background()
+	routing *routingBudget // routing lookups left for the request; nil gives each ranking a fresh budget
+}
 
-	for _, hash := range neighbors {
-		drivers, err := cache.Rdb.SMembers(ctx, fmt.Sprintf("drivers:%s", hash)).Result()
-		if err != nil {
-			continue
+// FindCandidates searches for available drivers around the pickup, widening the search ring by
+// ring (see searchRings) until drivers are found or the maximum pickup radius is reached.
+// Candidates are ordered from closest to farthest, with ties broken by driver ID.
+func FindCandidates(riderLat, riderLon float64) ([]Candidate, error) {
+	for _, ring := range searchRings() {
+		candidates, err := ring.find(riderLat, riderLon)
+		if err != ErrNoDriverAvailable {
+			return candidates, err
 		}
-		for _, driverStr := range drivers {
-			var driver models.Driver
-			json.Unmarshal([]byte(driverStr), &driver)
-			if driver.Status == "available" {
-				return &driver, nil
-			}
+	}
+	return nil, ErrNoDriverAvailable
+}
+
+// SearchRadii returns the successive radii, in km, of an expanding driver search: the
+// configured search radius, doubled each step and capped at the maximum pickup radius.
+func SearchRadii() []float64 {
+	radiusKm := config.GetFloat("matching.search_radius_km", 2)
+	maxRadiusKm := config.GetFloat("matching.max_pickup_radius_km", 10)
+	if radiusKm <= 0 || radiusKm > maxRadiusKm {
+		return []float64{maxRadiusKm}
+	}
+
+	var radii []float64
+	for ; radiusKm < maxRadiusKm; radiusKm *= 2 {
+		radii = append(radii, radiusKm)
+	}
+	return append(radii, maxRadiusKm)
+}
+
+// searchRing is one step of an expanding driver search: a radius around the pickup or, when
+// matching.candidate_source is "cells", the geohash cells around it at one precision.
+type searchRing struct {
+	radiusKm  float64
+	precision uint // zero for a radius search
+}
+
+// searchRings returns the steps of an expanding driver search. Radius searches follow
+// SearchRadii; cell searches go from the finest indexed precision to the coarsest, then search
+// the maximum pickup radius in case even the coarsest cells are smaller than it.
+func searchRings() []searchRing {
+	var rings []searchRing
+	if config.GetEnv("matching.candidate_source", "redis") == "cells" {
+		maxRadiusKm := config.GetFloat("matching.max_pickup_radius_km", 10)
+		for _, precision := range geohash.IndexPrecisions() {
+			rings = append(rings, searchRing{radiusKm: maxRadiusKm, precision: precision})
 		}
+		return append(rings, searchRing{radiusKm: maxRadiusKm})
+	}
+	for _, radiusKm := range SearchRadii() {
+		rings = append(rings, searchRing{radiusKm: radiusKm})
+	}
+	return rings
+}
+
+// find returns the available drivers in the ring, ordered from closest to farthest. A cell
+// search only keeps the drivers within the radius its cells are guaranteed to cover, since a
+// closer driver may sit just outside the neighbourhood; further drivers are left to a coarser
+// ring.
+func (ring searchRing) find(riderLat, riderLon float64) ([]Candidate, error) {
+	if ring.precision == 0 {
+		return FindCandidatesWithin(riderLat, riderLon, ring.radiusKm)
+	}
+
+	nearby, err := cache.SearchDriverCells(context.Background(), riderLat, riderLon, ring.precision)
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("no available drivers nearby")
+	coveredKm := math.Min(geohash.CellSizeKm(riderLat, ring.precision), ring.radiusKm)
+	return toCandidates(withinKm(nearby, coveredKm), coveredKm)
+}
+
+// withinKm keeps the drivers at most km away.
+func withinKm(nearby []cache.NearbyDriver, km float64) []cache.NearbyDriver {
+	var inRange []cache.NearbyDriver
+	for _, n := range nearby {
+		if n.DistanceKm <= km {
+			inRange = append(inRange, n)
+		}
+	}
+	return inRange
+}
+
+// FindCandidatesWithin returns every available driver within radiusKm of the pickup, ordered
+// from closest to farthest. Ties are broken by driver ID.
+func FindCandidatesWithin(riderLat, riderLon, radiusKm float64) ([]Candidate, error) {
+	nearby, err := cache.SearchAvailableDrivers(context.Background(), riderLat, riderLon, radiusKm)
+	if err != nil {
+		return nil, err
+	}
+	return toCandidates(nearby, radiusKm)
+}
+
+// toCandidates turns the drivers found by a search covering radiusKm into sorted candidates.
+func toCandidates(nearby []cache.NearbyDriver, radiusKm float64) ([]Candidate, error) {
+	if len(nearby) == 0 {
+		return nil, ErrNoDriverAvailable
+	}
+
+	now := time.Now()
+	candidates := make([]Candidate, len(nearby))
+	for i, n := range nearby {
+		candidates[i] = Candidate{
+			Driver:         n.Driver,
+			DistanceKm:     n.DistanceKm,
+			DistanceSource: DistanceSourceHaversine,
+			SearchRadiusKm: radiusKm,
+		}
+		if !n.AvailableSince.IsZero() {
+			candidates[i].IdleSeconds = now.Sub(n.AvailableSince).Seconds()
+		}
+	}
+
+	sortByDistance(candidates)
+	return candidates, nil
+}
+
+// FindNearestDriver returns the closest available driver to the rider's location.
+func FindNearestDriver(riderLat, riderLon float64) (*Candidate, error) {
+	candidates, err := FindCandidates(riderLat, riderLon)
+	if err != nil {
+		return nil, err
+	}
+	return &candidates[0], nil
+}
+
+// sortByDistance orders candidates by distance, then driver ID.
+func sortByDistance(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.DistanceKm != b.DistanceKm {
+			return a.DistanceKm < b.DistanceKm
+		}
+		return a.Driver.ID < b.Driver.ID
+	})
 }
@@ -0,0 +1,82 @@
+package matching
+
+import (
+	"context"
+	"rider-assignment-system/cache"
+	"rider-assignment-system/geohash"
+	"rider-assignment-system/internal/testutil"
+	"rider-assignment-system/models"
+	"testing"
+
+	mmgeohash "github.com/mmcloughlin/geohash"
+	"github.com/spf13/viper"
+)
+
+func TestSearchRingsGoFromFineCellsToCoarseThenRadius(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("matching.candidate_source", "cells")
+	viper.Set("matching.max_pickup_radius_km", 8)
+	viper.Set("geohash.index_precisions", []int{5, 7, 6})
+
+	want := []searchRing{{8, 7}, {8, 6}, {8, 5}, {8, 0}}
+	rings := searchRings()
+	if len(rings) != len(want) {
+		t.Fatalf("rings = %+v, want %+v", rings, want)
+	}
+	for i := range want {
+		if rings[i] != want[i] {
+			t.Errorf("ring %d = %+v, want %+v", i, rings[i], want[i])
+		}
+	}
+}
+
+func TestWithinKm(t *testing.T) {
+	nearby := []cache.NearbyDriver{
+		{Driver: models.Driver{ID: 1}, DistanceKm: 0.05},
+		{Driver: models.Driver{ID: 2}, DistanceKm: 0.2},
+		{Driver: models.Driver{ID: 3}, DistanceKm: 0.1},
+	}
+	kept := withinKm(nearby, 0.1)
+	if len(kept) != 2 || kept[0].Driver.ID != 1 || kept[1].Driver.ID != 3 {
+		t.Errorf("withinKm = %+v, want drivers 1 and 3", kept)
+	}
+}
+
+func TestFindCandidatesCoarsensPastUncoveredDrivers(t *testing.T) {
+	cache.Rdb = testutil.Integration(t)
+	defer viper.Reset()
+	viper.Set("matching.candidate_source", "cells")
+	viper.Set("geohash.index_precisions", []int{7, 6, 5})
+
+	// The rider waits near the east edge of a precision 7 cell. One driver sits in the far
+	// corner of the south-west neighbour, inside the neighbourhood but hundreds of meters away;
+	// the other just beyond the east neighbour, closer but outside it.
+	cell := mmgeohash.BoundingBox(geohash.Encode(52.52, 13.405, 7))
+	width, height := cell.MaxLng-cell.MinLng, cell.MaxLat-cell.MinLat
+	riderLat, riderLon := cell.MinLat+height/2, cell.MaxLng-width*0.05
+	inside := models.Driver{ID: 1, Name: "corner", Latitude: cell.MinLat - height*0.95, Longitude: cell.MinLng - width*0.95}
+	outside := models.Driver{ID: 2, Name: "beyond", Latitude: riderLat, Longitude: cell.MaxLng + width*1.05}
+
+	ctx := context.Background()
+	for _, driver := range []models.Driver{inside, outside} {
+		driver.Geohash = geohash.Encode(driver.Latitude, driver.Longitude, 12)
+		if err := cache.AddAvailableDriver(ctx, driver); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d1, d2 := geohash.Haversine(riderLat, riderLon, inside.Latitude, inside.Longitude),
+		geohash.Haversine(riderLat, riderLon, outside.Latitude, outside.Longitude); d2 >= d1 {
+		t.Fatalf("fixture: outside driver at %v km is not closer than %v km", d2, d1)
+	}
+
+	candidates, err := FindCandidates(riderLat, riderLon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if candidates[0].Driver.ID != outside.ID {
+		t.Errorf("nearest candidate is driver %d, want %d", candidates[0].Driver.ID, outside.ID)
+	}
+	if want := geohash.CellSizeKm(riderLat, 6); candidates[0].SearchRadiusKm != want {
+		t.Errorf("search radius %v km, want the precision 6 coverage %v km", candidates[0].SearchRadiusKm, want)
+	}
+}
@@ -0,0 +1,97 @@
+package matching
+
+import (
+	"database/sql"
+	"fmt"
+	"rider-assignment-system/geohash"
+	"rider-assignment-system/models"
+)
+
+// ReserveDriver claims the best candidate for req, as ranked by matcher, that is still available,
+// skipping any driver in exclude. The search widens ring by ring (see searchRings) until a
+// driver is claimed. The claim is a conditional update on the drivers table made inside tx, so
+// when two requests race for the same driver exactly one wins and the other moves on to its
+// next candidate.
+//
+// Routing lookups made by the matcher share one budget across the rings.
+//
+// The availability cache is not touched; callers remove the driver from it once tx commits.
+func ReserveDriver(tx *sql.Tx, matcher Matcher, req Request, exclude map[int64]bool) (*Candidate, error) {
+	req.routing = newRoutingBudget()
+	tried := make(map[int64]bool)
+	for _, ring := range searchRings() {
+		found, err := ring.find(req.PickupLat, req.PickupLon)
+		if err == ErrNoDriverAvailable {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var eligible []Candidate
+		for _, c := range found {
+			if !exclude[c.Driver.ID] && !tried[c.Driver.ID] {
+				eligible = append(eligible, c)
+			}
+		}
+		candidates := matcher.Rank(req, eligible)
+
+		for i := range candidates {
+			tried[candidates[i].Driver.ID] = true
+			claimed, err := ClaimDriver(tx, candidates[i].Driver.ID)
+			if err != nil {
+				return nil, err
+			}
+			if claimed {
+				return &candidates[i], nil
+			}
+		}
+	}
+	return nil, ErrNoDriverAvailable
+}
+
+// ReserveFromQueue claims the first driver of a queue, in queue order, who is still available and
+// not in exclude. It returns ErrNoDriverAvailable when nobody in the queue can be claimed.
+func ReserveFromQueue(tx *sql.Tx, req Request, queued []int64, exclude map[int64]bool) (*Candidate, error) {
+	for i, driverID := range queued {
+		if exclude[driverID] {
+			continue
+		}
+		driver := models.Driver{ID: driverID, Status: models.DriverReserved}
+		err := tx.QueryRow(
+			`UPDATE drivers SET status=$2 WHERE id=$1 AND status=$3
+             RETURNING name, latitude, longitude, geohash`,
+			driverID, models.DriverReserved, models.DriverAvailable,
+		).Scan(&driver.Name, &driver.Latitude, &driver.Longitude, &driver.Geohash)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve driver %d: %v", driverID, err)
+		}
+		return &Candidate{
+			Driver:         driver,
+			DistanceKm:     geohash.Haversine(driver.Latitude, driver.Longitude, req.PickupLat, req.PickupLon),
+			DistanceSource: DistanceSourceHaversine,
+			QueuePosition:  i + 1,
+		}, nil
+	}
+	return nil, ErrNoDriverAvailable
+}
+
+// ClaimDriver atomically flips a driver from 'available' to 'reserved' inside tx.
+// It reports false when the driver was no longer available.
+func ClaimDriver(tx *sql.Tx, driverID int64) (bool, error) {
+	result, err := tx.Exec(
+		`UPDATE drivers SET status=$2 WHERE id=$1 AND status=$3`,
+		driverID, models.DriverReserved, models.DriverAvailable,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve driver %d: %v", driverID, err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve driver %d: %v", driverID, err)
+	}
+	return rows == 1, nil
+}
@@ -0,0 +1,83 @@
+package matching
+
+import (
+	"math"
+	"math/rand"
+	"rider-assignment-system/geohash"
+)
+
+// SimulationResult compares greedy and batched matching of the same riders and drivers.
+type SimulationResult struct {
+	Riders           int
+	Drivers          int
+	GreedyMatched    int
+	GreedyPickupKm   float64 // total pickup distance when riders are matched one at a time
+	OptimalMatched   int
+	OptimalPickupKm  float64 // total pickup distance of the minimum-cost assignment
+	ImprovementRatio float64 // 1 - optimal/greedy over the riders both modes matched
+}
+
+// Simulate places riders and drivers uniformly at random in a square of sideKm centred on
+// (lat, lon) and matches them both greedily, in arrival order, and as one batch. Drivers further
+// than radiusKm from a rider are never matched to it.
+func Simulate(lat, lon, sideKm, radiusKm float64, riders, drivers int, seed int64) SimulationResult {
+	rng := rand.New(rand.NewSource(seed))
+	randomPoint := func() (float64, float64) {
+		dLat := (rng.Float64() - 0.5) * sideKm / 111.32
+		dLon := (rng.Float64() - 0.5) * sideKm / (111.32 * math.Cos(lat*math.Pi/180))
+		return lat + dLat, lon + dLon
+	}
+
+	type point struct{ lat, lon float64 }
+	riderPoints := make([]point, riders)
+	for i := range riderPoints {
+		riderPoints[i].lat, riderPoints[i].lon = randomPoint()
+	}
+	driverPoints := make([]point, drivers)
+	for j := range driverPoints {
+		driverPoints[j].lat, driverPoints[j].lon = randomPoint()
+	}
+
+	cost := make([][]float64, riders)
+	for i, r := range riderPoints {
+		cost[i] = make([]float64, drivers)
+		for j, d := range driverPoints {
+			distance := geohash.Haversine(r.lat, r.lon, d.lat, d.lon)
+			if distance > radiusKm {
+				distance = Unassignable
+			}
+			cost[i][j] = distance
+		}
+	}
+
+	result := SimulationResult{Riders: riders, Drivers: drivers}
+
+	// Greedy: each rider in turn takes the nearest driver still free
+	taken := make([]bool, drivers)
+	for i := range cost {
+		best := -1
+		for j := range cost[i] {
+			if !taken[j] && cost[i][j] < Unassignable && (best < 0 || cost[i][j] < cost[i][best]) {
+				best = j
+			}
+		}
+		if best >= 0 {
+			taken[best] = true
+			result.GreedyMatched++
+			result.GreedyPickupKm += cost[i][best]
+		}
+	}
+
+	// Batched: one minimum-cost assignment over everyone
+	for i, j := range SolveAssignment(cost) {
+		if j >= 0 {
+			result.OptimalMatched++
+			result.OptimalPickupKm += cost[i][j]
+		}
+	}
+
+	if result.GreedyPickupKm > 0 && result.GreedyMatched == result.OptimalMatched {
+		result.ImprovementRatio = 1 - result.OptimalPickupKm/result.GreedyPickupKm
+	}
+	return result
+}
@@ -0,0 +1,33 @@
+package matching
+
+import "testing"
+
+func TestSimulateBatchNeverCostsMoreThanGreedy(t *testing.T) {
+	scenarios := []struct {
+		name            string
+		riders, drivers int
+		sideKm          float64
+	}{
+		{"more drivers", 30, 40, 5},
+		{"more riders", 40, 30, 5},
+		{"sparse", 10, 10, 20},
+		{"dense", 60, 60, 2},
+	}
+	for _, sc := range scenarios {
+		for seed := int64(1); seed <= 20; seed++ {
+			result := Simulate(52.52, 13.405, sc.sideKm, 3, sc.riders, sc.drivers, seed)
+			if result.OptimalMatched < result.GreedyMatched {
+				t.Errorf("%s seed %d: batch matched %d riders, greedy %d", sc.name, seed, result.OptimalMatched, result.GreedyMatched)
+			}
+			if result.OptimalMatched == result.GreedyMatched && result.OptimalPickupKm > result.GreedyPickupKm+1e-9 {
+				t.Errorf("%s seed %d: batch pickup %.3f km, greedy %.3f km", sc.name, seed, result.OptimalPickupKm, result.GreedyPickupKm)
+			}
+		}
+	}
+}
+
+func BenchmarkSimulate(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Simulate(52.52, 13.405, 5, 3, 50, 60, int64(i))
+	}
+}
@@ -0,0 +1,216 @@
+package matching
+
+import (
+	"fmt"
+	"log"
+	"rider-assignment-system/config"
+	"rider-assignment-system/routing"
+	"sort"
+	"sync"
+)
+
+// Built-in strategy names
+const (
+	StrategyNearest     = "nearest"
+	StrategyETA         = "eta"
+	StrategyLongestIdle = "longest_idle"
+	StrategyWeighted    = "weighted"
+)
+
+// Matcher ranks the candidate drivers for a trip request, best first.
+type Matcher interface {
+	// Name is the identifier used to select the strategy in config and requests.
+	Name() string
+	// Rank returns the candidates ordered from most to least preferred.
+	// Implementations must not modify the input slice.
+	Rank(req Request, candidates []Candidate) []Candidate
+}
+
+// BatchMatcher is a strategy that can also price one driver for a trip, so batched trips are
+// assigned by the same criterion the strategy ranks single trips by. Strategies that do not
+// implement it are dispatched one trip at a time even when batching is enabled.
+type BatchMatcher interface {
+	Matcher
+	// Cost returns the cost of giving the trip to a candidate, lower being better. It is called
+	// with the candidates returned by Rank, so any ETAs Rank looked up are set.
+	Cost(req Request, candidate Candidate) float64
+}
+
+var (
+	matchers     = make(map[string]Matcher)
+	matchersLock sync.RWMutex
+)
+
+func init() {
+	Register(NearestMatcher{})
+	Register(ETAMatcher{})
+	Register(LongestIdleMatcher{})
+	Register(WeightedMatcher{})
+}
+
+// Register makes a matching strategy selectable by its name, replacing any strategy of the same name.
+func Register(m Matcher) {
+	matchersLock.Lock()
+	defer matchersLock.Unlock()
+	matchers[m.Name()] = m
+}
+
+// GetMatcher returns the strategy with the given name, or the configured default when name is empty.
+func GetMatcher(name string) (Matcher, error) {
+	if name == "" {
+		name = config.GetEnv("matching.strategy", StrategyNearest)
+	}
+	matchersLock.RLock()
+	defer matchersLock.RUnlock()
+	m, ok := matchers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown matching strategy %q", name)
+	}
+	return m, nil
+}
+
+// NearestMatcher prefers the driver closest to the pickup in a straight line.
+type NearestMatcher struct{}
+
+func (NearestMatcher) Name() string { return StrategyNearest }
+
+func (NearestMatcher) Rank(req Request, candidates []Candidate) []Candidate {
+	ranked := copyCandidates(candidates)
+	sortByDistance(ranked)
+	return ranked
+}
+
+func (NearestMatcher) Cost(req Request, candidate Candidate) float64 {
+	return candidate.DistanceKm
+}
+
+// ETAMatcher prefers the driver with the shortest road ETA to the pickup. Only the nearest few
+// candidates by straight-line distance are routed (see applyRoadETAs); the rest follow them by
+// distance, and all are ranked by distance when the routing service cannot be reached.
+type ETAMatcher struct{}
+
+func (ETAMatcher) Name() string { return StrategyETA }
+
+func (ETAMatcher) Rank(req Request, candidates []Candidate) []Candidate {
+	ranked := copyCandidates(candidates)
+	sortByDistance(ranked)
+	routed, err := applyRoadETAs(req, ranked)
+	if err != nil {
+		log.Printf("Falling back to haversine ranking: %v", err)
+		return ranked
+	}
+	shortlist := ranked[:routed]
+	sort.SliceStable(shortlist, func(i, j int) bool {
+		a, b := shortlist[i], shortlist[j]
+		if a.ETASeconds != b.ETASeconds {
+			return a.ETASeconds < b.ETASeconds
+		}
+		if a.DistanceKm != b.DistanceKm {
+			return a.DistanceKm < b.DistanceKm
+		}
+		return a.Driver.ID < b.Driver.ID
+	})
+	return ranked
+}
+
+// Cost is the candidate's ETA, estimated offline for candidates Rank did not route.
+func (ETAMatcher) Cost(req Request, candidate Candidate) float64 {
+	return etaSeconds(candidate)
+}
+
+// LongestIdleMatcher prefers the driver who has been waiting longest for a trip, so work is
+// spread fairly across the candidates in range.
+type LongestIdleMatcher struct{}
+
+func (LongestIdleMatcher) Name() string { return StrategyLongestIdle }
+
+func (LongestIdleMatcher) Rank(req Request, candidates []Candidate) []Candidate {
+	ranked := copyCandidates(candidates)
+	sortByDistance(ranked)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].IdleSeconds > ranked[j].IdleSeconds
+	})
+	return ranked
+}
+
+func (LongestIdleMatcher) Cost(req Request, candidate Candidate) float64 {
+	return -candidate.IdleSeconds
+}
+
+// WeightedMatcher scores each candidate as a weighted sum of pickup distance, road ETA and idle
+// time, preferring the lowest score. Weights come from matching.weights.* in config; the ETA
+// term is only used when ETAs are known, since looking them up costs routing calls, and then
+// the routed shortlist is ranked ahead of the other candidates.
+type WeightedMatcher struct{}
+
+func (WeightedMatcher) Name() string { return StrategyWeighted }
+
+func (WeightedMatcher) Rank(req Request, candidates []Candidate) []Candidate {
+	weights := weightsFromConfig()
+
+	ranked := copyCandidates(candidates)
+	sortByDistance(ranked)
+	routed := 0
+	if weights.etaMinute != 0 {
+		var err error
+		if routed, err = applyRoadETAs(req, ranked); err != nil {
+			log.Printf("Weighted matching without ETAs: %v", err)
+		}
+	}
+
+	for _, group := range [][]Candidate{ranked[:routed], ranked[routed:]} {
+		sort.SliceStable(group, func(i, j int) bool {
+			return weights.score(group[i], group[i].ETASeconds) < weights.score(group[j], group[j].ETASeconds)
+		})
+	}
+	return ranked
+}
+
+// Cost is the candidate's score. Candidates Rank did not route are scored with an offline ETA
+// estimate rather than ranked behind the routed ones, since a batch prices every pair at once.
+func (WeightedMatcher) Cost(req Request, candidate Candidate) float64 {
+	weights := weightsFromConfig()
+	eta := 0.0
+	if weights.etaMinute != 0 {
+		eta = etaSeconds(candidate)
+	}
+	return weights.score(candidate, eta)
+}
+
+// matchingWeights are the matching.weights.* settings of the weighted strategy.
+type matchingWeights struct {
+	distanceKm, etaMinute, idleMinute float64
+}
+
+func weightsFromConfig() matchingWeights {
+	return matchingWeights{
+		distanceKm: config.GetFloat("matching.weights.distance_km", 1),
+		etaMinute:  config.GetFloat("matching.weights.eta_minute", 0),
+		idleMinute: config.GetFloat("matching.weights.idle_minute", 0.1),
+	}
+}
+
+// score weighs a candidate given their ETA in seconds; lower is better.
+func (w matchingWeights) score(c Candidate, etaSeconds float64) float64 {
+	return w.distanceKm*c.DistanceKm + w.etaMinute*etaSeconds/60 - w.idleMinute*c.IdleSeconds/60
+}
+
+// etaSeconds returns a candidate's road ETA, or an offline estimate from their straight-line
+// distance when they were not routed.
+func etaSeconds(c Candidate) float64 {
+	if c.DistanceSource != DistanceSourceHaversine {
+		return c.ETASeconds
+	}
+	offline := routing.NewOfflineRouter(
+		config.GetFloat("routing.detour_factor", routing.DefaultDetourFactor),
+		config.GetFloat("routing.average_speed_kmh", routing.DefaultAverageSpeedKmh),
+	)
+	return c.DistanceKm * offline.DetourFactor / offline.AverageSpeedKmh * 3600
+}
+
+// copyCandidates returns a copy of candidates that a strategy may reorder freely.
+func copyCandidates(candidates []Candidate) []Candidate {
+	ranked := make([]Candidate, len(candidates))
+	copy(ranked, candidates)
+	return ranked
+}
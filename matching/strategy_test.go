@@ -0,0 +1,58 @@
+package matching
+
+import (
+	"rider-assignment-system/geohash"
+	"rider-assignment-system/models"
+	"rider-assignment-system/routing"
+	"sort"
+	"testing"
+)
+
+func TestBuiltInStrategiesCostAsTheyRank(t *testing.T) {
+	routing.SetDefault(routing.NewOfflineRouter(0, 0))
+	defer routing.SetDefault(nil)
+
+	req := Request{PickupLat: 52.52, PickupLon: 13.405}
+	var candidates []Candidate
+	for i, c := range []struct{ lat, lon, idle float64 }{
+		{52.521, 13.405, 60}, {52.53, 13.41, 900}, {52.515, 13.39, 300}, {52.5201, 13.4051, 0},
+	} {
+		candidates = append(candidates, Candidate{
+			Driver:         models.Driver{ID: int64(i + 1), Latitude: c.lat, Longitude: c.lon},
+			DistanceKm:     geohash.Haversine(req.PickupLat, req.PickupLon, c.lat, c.lon),
+			DistanceSource: DistanceSourceHaversine,
+			IdleSeconds:    c.idle,
+		})
+	}
+
+	for _, name := range []string{StrategyNearest, StrategyETA, StrategyLongestIdle, StrategyWeighted} {
+		matcher, err := GetMatcher(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		batchMatcher, ok := matcher.(BatchMatcher)
+		if !ok {
+			t.Fatalf("%s cannot be batched", name)
+		}
+
+		ranked := batchMatcher.Rank(req, candidates)
+		byCost := copyCandidates(ranked)
+		sort.SliceStable(byCost, func(i, j int) bool {
+			return batchMatcher.Cost(req, byCost[i]) < batchMatcher.Cost(req, byCost[j])
+		})
+		for i := range ranked {
+			if ranked[i].Driver.ID != byCost[i].Driver.ID {
+				t.Errorf("%s: rank order %v, cost order %v", name, driverIDs(ranked), driverIDs(byCost))
+				break
+			}
+		}
+	}
+}
+
+func driverIDs(candidates []Candidate) []int64 {
+	ids := make([]int64, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.Driver.ID
+	}
+	return ids
+}
@@ -13,16 +13,20 @@ import (
 	_ "github.com/lib/pq"
 )
 
-// RunMigrations runs the database migrations
-func RunMigrations() error {
-	// Database connection details from environment variables
+// databaseURL builds the connection string from environment variables
+func databaseURL() string {
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
 	dbUser := os.Getenv("DB_USER")
 	dbPassword := os.Getenv("DB_PASSWORD")
 	dbName := os.Getenv("DB_NAME")
 
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", dbUser, dbPassword, dbHost, dbPort, dbName)
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", dbUser, dbPassword, dbHost, dbPort, dbName)
+}
+
+// RunMigrations runs the database migrations
+func RunMigrations() error {
+	dsn := databaseURL()
 
 	// Retry connecting to the database to ensure it's ready
 	var db *sql.DB
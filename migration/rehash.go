@@ -0,0 +1,68 @@
+package migration
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"rider-assignment-system/geohash"
+)
+
+// RehashDrivers re-encodes every stored driver geohash at the precision now configured for
+// its location (geohash.precision and geohash.zones). Run it after changing either setting;
+// the availability indexes are rebuilt from coordinates at startup and need no migration.
+// Drivers who move while it runs are skipped, as their location update stored a fresh geohash.
+func RehashDrivers() error {
+	db, err := sql.Open("postgres", databaseURL())
+	if err != nil {
+		return fmt.Errorf("could not connect to the database: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(
+		`SELECT id, latitude, longitude, COALESCE(geohash, '') FROM drivers
+         WHERE latitude IS NOT NULL AND longitude IS NOT NULL`,
+	)
+	if err != nil {
+		return fmt.Errorf("could not load drivers: %v", err)
+	}
+	type rehash struct {
+		lat, lon float64
+		hash     string
+	}
+	updates := make(map[int64]rehash)
+	for rows.Next() {
+		var id int64
+		var lat, lon float64
+		var current string
+		if err := rows.Scan(&id, &lat, &lon, &current); err != nil {
+			rows.Close()
+			return err
+		}
+		if current == "" {
+			continue // drivers registered without a location are not indexed
+		}
+		if hash := geohash.EncodeAt(lat, lon); hash != current {
+			updates[id] = rehash{lat: lat, lon: lon, hash: hash}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rehashed := 0
+	for id, update := range updates {
+		result, err := db.Exec(
+			`UPDATE drivers SET geohash=$1 WHERE id=$2 AND latitude=$3 AND longitude=$4`,
+			update.hash, id, update.lat, update.lon,
+		)
+		if err != nil {
+			return fmt.Errorf("could not rehash driver %d: %v", id, err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 1 {
+			rehashed++
+		}
+	}
+	log.Printf("Rehashed %d driver geohashes, skipped %d drivers who moved meanwhile.", rehashed, len(updates)-rehashed)
+	return nil
+}
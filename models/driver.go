@@ -1,10 +1,39 @@
 package models
 
+import "time"
+
+// Driver statuses
+const (
+	DriverAvailable = "available"
+	DriverReserved  = "reserved"
+	DriverOnTrip    = "on_trip"
+	DriverOffline   = "offline"
+)
+
+// IsDriverStatus reports whether status is a known driver status.
+func IsDriverStatus(status string) bool {
+	return status == DriverAvailable || status == DriverReserved || status == DriverOnTrip || status == DriverOffline
+}
+
+// CanDriverSetStatus reports whether a driver may move themselves from one status to another.
+// Drivers only go available or offline themselves; a driver held for or on a trip keeps that
+// status until the offer and trip endpoints release them.
+func CanDriverSetStatus(from, to string) bool {
+	if from == to {
+		return true
+	}
+	if from == DriverReserved || from == DriverOnTrip {
+		return false
+	}
+	return to == DriverAvailable || to == DriverOffline
+}
+
 type Driver struct {
-	ID        int64   `json:"id"`
-	Name      string  `json:"name"`
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-	Geohash   string  `json:"geohash"`
-	Status    string  `json:"status"` // "available", "on_trip"
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Latitude   float64    `json:"latitude"`
+	Longitude  float64    `json:"longitude"`
+	Geohash    string     `json:"geohash"`
+	Status     string     `json:"status"`                 // "available", "reserved", "on_trip", "offline"
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"` // time of the driver's last location ping
 }
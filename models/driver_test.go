@@ -0,0 +1,40 @@
+package models
+
+import "testing"
+
+func TestCanDriverSetStatus(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{DriverAvailable, DriverOffline, true},
+		{DriverOffline, DriverAvailable, true},
+		{DriverAvailable, DriverAvailable, true},
+		{DriverReserved, DriverReserved, true},
+		{DriverOnTrip, DriverOnTrip, true},
+		{DriverReserved, DriverAvailable, false},
+		{DriverOnTrip, DriverAvailable, false},
+		{DriverReserved, DriverOffline, false},
+		{DriverOnTrip, DriverOffline, false},
+		{DriverAvailable, DriverReserved, false},
+		{DriverOffline, DriverOnTrip, false},
+	}
+	for _, tt := range tests {
+		if got := CanDriverSetStatus(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanDriverSetStatus(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestIsDriverStatus(t *testing.T) {
+	for _, status := range []string{DriverAvailable, DriverReserved, DriverOnTrip, DriverOffline} {
+		if !IsDriverStatus(status) {
+			t.Errorf("IsDriverStatus(%q) = false", status)
+		}
+	}
+	for _, status := range []string{"", "busy", "Available"} {
+		if IsDriverStatus(status) {
+			t.Errorf("IsDriverStatus(%q) = true", status)
+		}
+	}
+}
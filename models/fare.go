@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// VehicleStandard is the vehicle class priced when a request names none.
+const VehicleStandard = "standard"
+
+// PricingPlan holds the rates charged for a vehicle class in a zone.
+type PricingPlan struct {
+	ID           int64     `json:"id"`
+	ZoneID       *int64    `json:"zone_id,omitempty"` // nil for the plan used outside every priced zone
+	VehicleClass string    `json:"vehicle_class"`
+	Currency     string    `json:"currency"`
+	BaseFare     float64   `json:"base_fare"`
+	PerKm        float64   `json:"per_km"`
+	PerMinute    float64   `json:"per_minute"`
+	BookingFee   float64   `json:"booking_fee"`
+	MinimumFare  float64   `json:"minimum_fare"` // least charged before the booking fee
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// FareBreakdown itemises a fare. Total is the sum of the other items.
+type FareBreakdown struct {
+	BaseFare              float64 `json:"base_fare"`
+	DistanceFare          float64 `json:"distance_fare"`
+	TimeFare              float64 `json:"time_fare"`
+	MinimumFareAdjustment float64 `json:"minimum_fare_adjustment"` // added to reach the plan's minimum fare
+	SurgeMultiplier       float64 `json:"surge_multiplier"`
+	SurgeFare             float64 `json:"surge_fare"` // added by the surge multiplier
+	BookingFee            float64 `json:"booking_fee"`
+	Total                 float64 `json:"total"`
+}
+
+// FareEstimate is an upfront quote for a ride, which a trip can reference before it expires to
+// be charged its total.
+type FareEstimate struct {
+	ID              int64         `json:"id"`
+	RiderID         *int64        `json:"rider_id,omitempty"`
+	PlanID          int64         `json:"plan_id"`
+	VehicleClass    string        `json:"vehicle_class"`
+	Currency        string        `json:"currency"`
+	StartLat        float64       `json:"start_latitude"`
+	StartLon        float64       `json:"start_longitude"`
+	EndLat          float64       `json:"end_latitude"`
+	EndLon          float64       `json:"end_longitude"`
+	DistanceKm      float64       `json:"distance_km"`
+	DurationSeconds float64       `json:"duration_seconds"`
+	RoutingProvider string        `json:"routing_provider"`
+	Breakdown       FareBreakdown `json:"breakdown"`
+	CreatedAt       time.Time     `json:"created_at"`
+	ExpiresAt       time.Time     `json:"expires_at"`
+	TripID          *int64        `json:"trip_id,omitempty"` // trip that locked the price, if any
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Offer statuses
+const (
+	OfferPending   = "pending"
+	OfferAccepted  = "accepted"
+	OfferDeclined  = "declined"
+	OfferExpired   = "expired"
+	OfferCancelled = "cancelled"
+)
+
+// Offer is a request for a driver to take a trip, held open until the driver responds or it expires.
+type Offer struct {
+	ID          int64      `json:"id"`
+	TripID      int64      `json:"trip_id"`
+	DriverID    int64      `json:"driver_id"`
+	Status      string     `json:"status"` // one of the Offer* status constants
+	DistanceKm  float64    `json:"distance_km"`
+	ETASeconds  float64    `json:"eta_seconds,omitempty"`
+	StartLat    float64    `json:"start_latitude"`
+	StartLon    float64    `json:"start_longitude"`
+	EndLat      float64    `json:"end_latitude"`
+	EndLon      float64    `json:"end_longitude"`
+	OfferedAt   time.Time  `json:"offered_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
@@ -1,5 +1,28 @@
 package models
 
+import "time"
+
+// Trip statuses, in lifecycle order. Completed, cancelled and expired are terminal.
+const (
+	TripRequested      = "requested"
+	TripDriverAssigned = "driver_assigned"
+	TripAccepted       = "accepted"
+	TripDriverArrived  = "driver_arrived"
+	TripInProgress     = "in_progress"
+	TripCompleted      = "completed"
+	TripCancelled      = "cancelled"
+	TripExpired        = "expired"
+)
+
+// tripTransitions lists the statuses a trip may move to from each status.
+var tripTransitions = map[string][]string{
+	TripRequested:      {TripDriverAssigned, TripCancelled, TripExpired},
+	TripDriverAssigned: {TripAccepted, TripRequested, TripCancelled, TripExpired}, // back to requested when an offer is declined
+	TripAccepted:       {TripDriverArrived, TripCancelled},
+	TripDriverArrived:  {TripInProgress, TripCancelled},
+	TripInProgress:     {TripCompleted},
+}
+
 type Trip struct {
 	ID       int64   `json:"id"`
 	RiderID  int64   `json:"rider_id"`
@@ -8,5 +31,47 @@ type Trip struct {
 	StartLon float64 `json:"start_longitude"`
 	EndLat   float64 `json:"end_latitude"`
 	EndLon   float64 `json:"end_longitude"`
-	Status   string  `json:"status"` // "requested", "accepted", "completed"
+	Status   string  `json:"status"` // one of the Trip* status constants
+
+	// Road ETA in seconds from the assigned driver to the pickup; nil when not routed
+	ETASeconds *float64 `json:"eta_seconds,omitempty"`
+
+	// Upfront fare estimate the trip was requested with, and the fare it locked in
+	FareEstimateID  *int64   `json:"fare_estimate_id,omitempty"`
+	Fare            *float64 `json:"fare,omitempty"`
+	SurgeMultiplier *float64 `json:"surge_multiplier,omitempty"` // at the pickup when requested, or locked by the estimate
+
+	// Most specific zones containing the pickup and dropoff; nil outside every zone
+	PickupZoneID  *int64 `json:"pickup_zone_id,omitempty"`
+	DropoffZoneID *int64 `json:"dropoff_zone_id,omitempty"`
+
+	// Time the trip entered each status; nil for statuses it has not reached.
+	RequestedAt      *time.Time `json:"requested_at,omitempty"`
+	DriverAssignedAt *time.Time `json:"driver_assigned_at,omitempty"`
+	AcceptedAt       *time.Time `json:"accepted_at,omitempty"`
+	DriverArrivedAt  *time.Time `json:"driver_arrived_at,omitempty"`
+	InProgressAt     *time.Time `json:"in_progress_at,omitempty"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	CancelledAt      *time.Time `json:"cancelled_at,omitempty"`
+	ExpiredAt        *time.Time `json:"expired_at,omitempty"`
+}
+
+// CanTransition reports whether a trip may move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, next := range tripTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminalTripStatus reports whether a trip in this status can no longer change.
+func IsTerminalTripStatus(status string) bool {
+	return status == TripCompleted || status == TripCancelled || status == TripExpired
+}
+
+// TripStatusTimestampColumn returns the trips column recording when a trip entered status.
+func TripStatusTimestampColumn(status string) string {
+	return status + "_at"
 }
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Zone kinds
+const (
+	ZoneServiceArea = "service_area" // rides may only start and end inside a service area
+	ZoneAirport     = "airport"
+	ZoneNoPickup    = "no_pickup" // riders cannot be picked up here
+	ZoneQueue       = "queue"     // staging lot where available drivers wait in line
+)
+
+// Zone is a named geofence.
+type Zone struct {
+	ID        int64           `json:"id"`
+	Name      string          `json:"name"`
+	Kind      string          `json:"kind"`     // one of the Zone* kind constants
+	Geometry  json.RawMessage `json:"geometry"` // GeoJSON Polygon or MultiPolygon
+	CreatedAt time.Time       `json:"created_at"`
+
+	// QueueZoneID names the queue zone whose drivers serve pickups in this zone, first in first out
+	QueueZoneID *int64 `json:"queue_zone_id,omitempty"`
+}
+
+// IsZoneKind reports whether kind is a known zone kind.
+func IsZoneKind(kind string) bool {
+	return kind == ZoneServiceArea || kind == ZoneAirport || kind == ZoneNoPickup || kind == ZoneQueue
+}
@@ -0,0 +1,62 @@
+package presence
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"rider-assignment-system/cache"
+	"rider-assignment-system/database"
+	"time"
+)
+
+// EvictStaleDrivers marks available drivers that have not pinged within ttl as offline and
+// removes them from the availability index. It returns the number of drivers evicted.
+func EvictStaleDrivers(ctx context.Context, ttl time.Duration) (int, error) {
+	rows, err := database.DB.QueryContext(ctx,
+		`UPDATE drivers SET status='offline'
+         WHERE status='available' AND last_seen_at < now() - $1 * interval '1 millisecond'
+         RETURNING id`,
+		ttl.Milliseconds(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to evict stale drivers: %v", err)
+	}
+	defer rows.Close()
+
+	var driverIDs []int64
+	for rows.Next() {
+		var driverID int64
+		if err := rows.Scan(&driverID); err != nil {
+			return 0, err
+		}
+		driverIDs = append(driverIDs, driverID)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, driverID := range driverIDs {
+		driverID := driverID
+		cache.ApplyUpdate("remove offline driver", func() error {
+			return cache.RemoveAvailableDriver(ctx, driverID)
+		})
+	}
+	return len(driverIDs), nil
+}
+
+// StartSweeper periodically evicts drivers whose heartbeats have stopped.
+func StartSweeper(interval, ttl time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			evicted, err := EvictStaleDrivers(context.Background(), ttl)
+			if err != nil {
+				log.Printf("Heartbeat sweep failed: %v", err)
+			}
+			if evicted > 0 {
+				log.Printf("Marked %d drivers offline after missed heartbeats", evicted)
+			}
+		}
+	}()
+}
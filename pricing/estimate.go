@@ -0,0 +1,174 @@
+package pricing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"rider-assignment-system/config"
+	"rider-assignment-system/database"
+	"rider-assignment-system/geofence"
+	"rider-assignment-system/geohash"
+	"rider-assignment-system/models"
+	"rider-assignment-system/routing"
+	"rider-assignment-system/surge"
+	"time"
+)
+
+var (
+	// ErrEstimateNotFound is returned when a fare estimate does not exist.
+	ErrEstimateNotFound = errors.New("fare estimate not found")
+	// ErrEstimateExpired is returned when a fare estimate is referenced after its expiry.
+	ErrEstimateExpired = errors.New("fare estimate has expired")
+	// ErrEstimateUsed is returned when a fare estimate has already locked the price of a trip.
+	ErrEstimateUsed = errors.New("fare estimate has already been used")
+	// ErrEstimateMismatch is returned when a trip differs from the ride its estimate quoted.
+	ErrEstimateMismatch = errors.New("fare estimate is for a different rider or route")
+)
+
+// EstimateRequest describes the ride to quote, in the shape of a trip request.
+type EstimateRequest struct {
+	RiderID      int64 // optional; when set only this rider's trips can use the estimate
+	StartLat     float64
+	StartLon     float64
+	EndLat       float64
+	EndLon       float64
+	VehicleClass string // empty for the standard class
+}
+
+// estimateColumns are the fare_estimates columns scanned by scanEstimate
+const estimateColumns = `id, rider_id, plan_id, vehicle_class, currency, start_latitude, start_longitude,
+        end_latitude, end_longitude, distance_km, duration_seconds, routing_provider, base_fare, distance_fare,
+        time_fare, minimum_fare_adjustment, surge_multiplier, surge_fare, booking_fee, total, created_at,
+        expires_at, trip_id`
+
+// Estimate quotes a ride under the pricing plan at its pickup, from the road distance and
+// duration between pickup and dropoff and the current surge at the pickup. The quote is stored
+// so that a trip requested before pricing.estimate_ttl elapses can lock its price. Rides the
+// geofences do not allow are rejected with the geofence errors.
+func Estimate(ctx context.Context, req EstimateRequest) (*models.FareEstimate, error) {
+	if _, err := geofence.CheckPickup(req.StartLat, req.StartLon); err != nil {
+		return nil, err
+	}
+	if _, err := geofence.CheckDropoff(req.EndLat, req.EndLon); err != nil {
+		return nil, err
+	}
+	if req.VehicleClass == "" {
+		req.VehicleClass = models.VehicleStandard
+	}
+
+	plan, err := PlanFor(ctx, req.StartLat, req.StartLon, req.VehicleClass)
+	if err != nil {
+		return nil, err
+	}
+	route, err := routing.Default().Route(ctx,
+		routing.Point{Lat: req.StartLat, Lon: req.StartLon}, routing.Point{Lat: req.EndLat, Lon: req.EndLon})
+	if err != nil {
+		return nil, fmt.Errorf("failed to route the ride: %v", err)
+	}
+
+	estimate := models.FareEstimate{
+		PlanID:          plan.ID,
+		VehicleClass:    req.VehicleClass,
+		Currency:        plan.Currency,
+		StartLat:        req.StartLat,
+		StartLon:        req.StartLon,
+		EndLat:          req.EndLat,
+		EndLon:          req.EndLon,
+		DistanceKm:      route.DistanceKm(),
+		DurationSeconds: route.DurationSeconds,
+		RoutingProvider: route.Provider,
+		Breakdown: ComputeFare(*plan, route.DistanceKm(), route.DurationSeconds,
+			surge.MultiplierAt(ctx, req.StartLat, req.StartLon).Multiplier),
+	}
+	if req.RiderID > 0 {
+		estimate.RiderID = &req.RiderID
+	}
+
+	fare := estimate.Breakdown
+	err = database.DB.QueryRowContext(ctx,
+		`INSERT INTO fare_estimates (rider_id, plan_id, vehicle_class, currency, start_latitude, start_longitude,
+                                     end_latitude, end_longitude, distance_km, duration_seconds, routing_provider,
+                                     base_fare, distance_fare, time_fare, minimum_fare_adjustment, surge_multiplier,
+                                     surge_fare, booking_fee, total, expires_at)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19,
+                 now() + $20 * interval '1 millisecond')
+         RETURNING id, created_at, expires_at`,
+		estimate.RiderID, estimate.PlanID, estimate.VehicleClass, estimate.Currency, estimate.StartLat, estimate.StartLon,
+		estimate.EndLat, estimate.EndLon, estimate.DistanceKm, estimate.DurationSeconds, estimate.RoutingProvider,
+		fare.BaseFare, fare.DistanceFare, fare.TimeFare, fare.MinimumFareAdjustment, fare.SurgeMultiplier,
+		fare.SurgeFare, fare.BookingFee, fare.Total, estimateTTL().Milliseconds(),
+	).Scan(&estimate.ID, &estimate.CreatedAt, &estimate.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &estimate, nil
+}
+
+// LockEstimate marks an estimate as used by a trip inside tx and returns it, so the trip is
+// charged the quoted total. The estimate must be unexpired and unused, quote the same rider
+// (when it names one), and have its pickup and dropoff within pricing.estimate_match_meters of
+// the trip's.
+func LockEstimate(tx *sql.Tx, estimateID, tripID, riderID int64, startLat, startLon, endLat, endLon float64) (*models.FareEstimate, error) {
+	var expired bool
+	estimate, err := scanEstimate(tx.QueryRow(
+		`SELECT `+estimateColumns+`, expires_at <= now() FROM fare_estimates WHERE id=$1 FOR UPDATE`,
+		estimateID,
+	), &expired)
+	if err == sql.ErrNoRows {
+		return nil, ErrEstimateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkEstimate(estimate, expired, riderID, startLat, startLon, endLat, endLon); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE fare_estimates SET trip_id=$1 WHERE id=$2`, tripID, estimateID); err != nil {
+		return nil, err
+	}
+	estimate.TripID = &tripID
+	return estimate, nil
+}
+
+// checkEstimate applies the rules of LockEstimate to an estimate read from the database
+func checkEstimate(estimate *models.FareEstimate, expired bool, riderID int64, startLat, startLon, endLat, endLon float64) error {
+	switch {
+	case estimate.TripID != nil:
+		return ErrEstimateUsed
+	case expired:
+		return ErrEstimateExpired
+	case estimate.RiderID != nil && *estimate.RiderID != riderID:
+		return ErrEstimateMismatch
+	}
+	tolerance := config.GetFloat("pricing.estimate_match_meters", 200) / 1000
+	if geohash.Haversine(estimate.StartLat, estimate.StartLon, startLat, startLon) > tolerance ||
+		geohash.Haversine(estimate.EndLat, estimate.EndLon, endLat, endLon) > tolerance {
+		return ErrEstimateMismatch
+	}
+	return nil
+}
+
+// scanEstimate reads a row of estimateColumns followed by any extra columns
+func scanEstimate(row *sql.Row, extra ...interface{}) (*models.FareEstimate, error) {
+	var estimate models.FareEstimate
+	fare := &estimate.Breakdown
+	dest := []interface{}{
+		&estimate.ID, &estimate.RiderID, &estimate.PlanID, &estimate.VehicleClass, &estimate.Currency,
+		&estimate.StartLat, &estimate.StartLon, &estimate.EndLat, &estimate.EndLon, &estimate.DistanceKm,
+		&estimate.DurationSeconds, &estimate.RoutingProvider, &fare.BaseFare, &fare.DistanceFare, &fare.TimeFare,
+		&fare.MinimumFareAdjustment, &fare.SurgeMultiplier, &fare.SurgeFare, &fare.BookingFee, &fare.Total,
+		&estimate.CreatedAt, &estimate.ExpiresAt, &estimate.TripID,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &estimate, nil
+}
+
+// estimateTTL is how long an estimate can be used to lock a trip's price
+func estimateTTL() time.Duration {
+	return config.GetDuration("pricing.estimate_ttl", 5*time.Minute)
+}
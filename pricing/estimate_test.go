@@ -0,0 +1,48 @@
+package pricing
+
+import (
+	"rider-assignment-system/models"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestCheckEstimate(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("pricing.estimate_match_meters", 200)
+
+	rider, otherRider, tripID := int64(7), int64(8), int64(3)
+	quote := func(riderID, tripID *int64) *models.FareEstimate {
+		return &models.FareEstimate{RiderID: riderID, TripID: tripID, StartLat: 52.5, StartLon: 13.4, EndLat: 52.52, EndLon: 13.45}
+	}
+	// 0.001 degrees of latitude is about 111 meters, 0.002 about 222
+	tests := []struct {
+		name                               string
+		estimate                           *models.FareEstimate
+		expired                            bool
+		riderID                            int64
+		startLat, startLon, endLat, endLon float64
+		want                               error
+	}{
+		{"same ride", quote(&rider, nil), false, rider, 52.5, 13.4, 52.52, 13.45, nil},
+		{"estimate without a rider", quote(nil, nil), false, otherRider, 52.5, 13.4, 52.52, 13.45, nil},
+		{"pickup and dropoff within tolerance", quote(&rider, nil), false, rider, 52.501, 13.4, 52.519, 13.45, nil},
+		{"already used", quote(&rider, &tripID), false, rider, 52.5, 13.4, 52.52, 13.45, ErrEstimateUsed},
+		{"used takes precedence over expired", quote(&rider, &tripID), true, rider, 52.5, 13.4, 52.52, 13.45, ErrEstimateUsed},
+		{"expired", quote(&rider, nil), true, rider, 52.5, 13.4, 52.52, 13.45, ErrEstimateExpired},
+		{"other rider", quote(&rider, nil), false, otherRider, 52.5, 13.4, 52.52, 13.45, ErrEstimateMismatch},
+		{"pickup moved", quote(&rider, nil), false, rider, 52.502, 13.4, 52.52, 13.45, ErrEstimateMismatch},
+		{"dropoff moved", quote(&rider, nil), false, rider, 52.5, 13.4, 52.522, 13.45, ErrEstimateMismatch},
+	}
+	for _, tt := range tests {
+		got := checkEstimate(tt.estimate, tt.expired, tt.riderID, tt.startLat, tt.startLon, tt.endLat, tt.endLon)
+		if got != tt.want {
+			t.Errorf("%s: checkEstimate() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	viper.Set("pricing.estimate_match_meters", 300)
+	if err := checkEstimate(quote(&rider, nil), false, rider, 52.502, 13.4, 52.52, 13.45); err != nil {
+		t.Errorf("checkEstimate() with a 300 m tolerance = %v, want nil", err)
+	}
+}
@@ -0,0 +1,32 @@
+package pricing
+
+import (
+	"math"
+	"rider-assignment-system/models"
+)
+
+// ComputeFare prices a ride of the given road distance and duration under a plan. The base,
+// distance and time fares are topped up to the plan's minimum fare and multiplied by the surge
+// multiplier (1 for none), then the booking fee is added. Every item is rounded to cents.
+func ComputeFare(plan models.PricingPlan, distanceKm, durationSeconds, surgeMultiplier float64) models.FareBreakdown {
+	fare := models.FareBreakdown{
+		BaseFare:        roundCents(plan.BaseFare),
+		DistanceFare:    roundCents(plan.PerKm * distanceKm),
+		TimeFare:        roundCents(plan.PerMinute * durationSeconds / 60),
+		SurgeMultiplier: math.Max(surgeMultiplier, 1),
+		BookingFee:      roundCents(plan.BookingFee),
+	}
+	subtotal := fare.BaseFare + fare.DistanceFare + fare.TimeFare
+	if subtotal < plan.MinimumFare {
+		fare.MinimumFareAdjustment = roundCents(plan.MinimumFare - subtotal)
+		subtotal += fare.MinimumFareAdjustment
+	}
+	fare.SurgeFare = roundCents(subtotal * (fare.SurgeMultiplier - 1))
+	fare.Total = roundCents(subtotal + fare.SurgeFare + fare.BookingFee)
+	return fare
+}
+
+// roundCents rounds an amount to two decimal places
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,51 @@
+package pricing
+
+import (
+	"rider-assignment-system/models"
+	"testing"
+)
+
+func TestComputeFare(t *testing.T) {
+	plan := models.PricingPlan{BaseFare: 2.5, PerKm: 1.2, PerMinute: 0.3, BookingFee: 1, MinimumFare: 7}
+	tests := []struct {
+		name            string
+		plan            models.PricingPlan
+		distanceKm      float64
+		durationSeconds float64
+		surge           float64
+		want            models.FareBreakdown
+	}{
+		{
+			name: "per km and per minute", plan: plan, distanceKm: 10, durationSeconds: 1200, surge: 1,
+			want: models.FareBreakdown{BaseFare: 2.5, DistanceFare: 12, TimeFare: 6, SurgeMultiplier: 1, BookingFee: 1, Total: 21.5},
+		},
+		{
+			name: "topped up to the minimum fare before the booking fee", plan: plan, distanceKm: 1, durationSeconds: 120, surge: 1,
+			want: models.FareBreakdown{BaseFare: 2.5, DistanceFare: 1.2, TimeFare: 0.6, MinimumFareAdjustment: 2.7,
+				SurgeMultiplier: 1, BookingFee: 1, Total: 8},
+		},
+		{
+			name: "surge multiplies the fare after the minimum but not the booking fee", plan: plan, distanceKm: 1, durationSeconds: 120, surge: 1.5,
+			want: models.FareBreakdown{BaseFare: 2.5, DistanceFare: 1.2, TimeFare: 0.6, MinimumFareAdjustment: 2.7,
+				SurgeMultiplier: 1.5, SurgeFare: 3.5, BookingFee: 1, Total: 11.5},
+		},
+		{
+			name: "surge above the minimum", plan: plan, distanceKm: 10, durationSeconds: 1200, surge: 2,
+			want: models.FareBreakdown{BaseFare: 2.5, DistanceFare: 12, TimeFare: 6, SurgeMultiplier: 2, SurgeFare: 20.5,
+				BookingFee: 1, Total: 42},
+		},
+		{
+			name: "a multiplier below one is no surge", plan: plan, distanceKm: 10, durationSeconds: 1200, surge: 0.5,
+			want: models.FareBreakdown{BaseFare: 2.5, DistanceFare: 12, TimeFare: 6, SurgeMultiplier: 1, BookingFee: 1, Total: 21.5},
+		},
+		{
+			name: "items are rounded to cents", plan: models.PricingPlan{PerKm: 1.234, PerMinute: 0.333}, distanceKm: 3, durationSeconds: 90, surge: 1,
+			want: models.FareBreakdown{DistanceFare: 3.7, TimeFare: 0.5, SurgeMultiplier: 1, Total: 4.2},
+		},
+	}
+	for _, tt := range tests {
+		if got := ComputeFare(tt.plan, tt.distanceKm, tt.durationSeconds, tt.surge); got != tt.want {
+			t.Errorf("%s: ComputeFare() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package pricing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"rider-assignment-system/database"
+	"rider-assignment-system/geofence"
+	"rider-assignment-system/models"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNoPricingPlan is returned when no plan prices a vehicle class at a pickup.
+	ErrNoPricingPlan = errors.New("no pricing plan for this vehicle class at the pickup")
+	// ErrPlanExists is returned when a zone already has a plan for the vehicle class.
+	ErrPlanExists = errors.New("a pricing plan already exists for this zone and vehicle class")
+)
+
+// planColumns are the pricing_plans columns scanned by scanPlan
+const planColumns = `id, zone_id, vehicle_class, currency, base_fare, per_km, per_minute, booking_fee, minimum_fare, created_at`
+
+// ValidatePlan checks a plan before it is stored, filling in the default vehicle class.
+func ValidatePlan(plan *models.PricingPlan) error {
+	if plan.VehicleClass == "" {
+		plan.VehicleClass = models.VehicleStandard
+	}
+	if len(plan.Currency) != 3 {
+		return fmt.Errorf("currency must be a three-letter code, got %q", plan.Currency)
+	}
+	plan.Currency = strings.ToUpper(plan.Currency)
+	for name, rate := range map[string]float64{
+		"base_fare":    plan.BaseFare,
+		"per_km":       plan.PerKm,
+		"per_minute":   plan.PerMinute,
+		"booking_fee":  plan.BookingFee,
+		"minimum_fare": plan.MinimumFare,
+	} {
+		if rate < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// CreatePlan stores a new pricing plan.
+func CreatePlan(ctx context.Context, plan models.PricingPlan) (*models.PricingPlan, error) {
+	if err := ValidatePlan(&plan); err != nil {
+		return nil, err
+	}
+	err := database.DB.QueryRowContext(ctx,
+		`INSERT INTO pricing_plans (zone_id, vehicle_class, currency, base_fare, per_km, per_minute, booking_fee, minimum_fare)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`,
+		plan.ZoneID, plan.VehicleClass, plan.Currency, plan.BaseFare, plan.PerKm, plan.PerMinute, plan.BookingFee, plan.MinimumFare,
+	).Scan(&plan.ID, &plan.CreatedAt)
+	if pgErr, ok := err.(*pq.Error); ok {
+		switch pgErr.Code.Name() {
+		case "unique_violation":
+			return nil, ErrPlanExists
+		case "foreign_key_violation":
+			return nil, geofence.ErrZoneNotFound
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// ListPlans returns every pricing plan, ordered by ID.
+func ListPlans(ctx context.Context) ([]models.PricingPlan, error) {
+	rows, err := database.DB.QueryContext(ctx, `SELECT `+planColumns+` FROM pricing_plans ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []models.PricingPlan{}
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *plan)
+	}
+	return plans, rows.Err()
+}
+
+// PlanFor returns the plan pricing a vehicle class at a pickup: the plan of the most specific
+// zone containing the pickup that has one, or else the plan without a zone.
+func PlanFor(ctx context.Context, lat, lon float64, vehicleClass string) (*models.PricingPlan, error) {
+	zones := geofence.ZonesAt(lat, lon)
+	zoneIDs := make([]int64, len(zones))
+	for i, zone := range zones {
+		zoneIDs[i] = zone.ID
+	}
+
+	rows, err := database.DB.QueryContext(ctx,
+		`SELECT `+planColumns+` FROM pricing_plans
+         WHERE vehicle_class=$1 AND (zone_id IS NULL OR zone_id = ANY($2))`,
+		vehicleClass, pq.Array(zoneIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byZone := make(map[int64]*models.PricingPlan)
+	var fallback *models.PricingPlan
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		if plan.ZoneID == nil {
+			fallback = plan
+		} else {
+			byZone[*plan.ZoneID] = plan
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range zoneIDs {
+		if plan, ok := byZone[id]; ok {
+			return plan, nil
+		}
+	}
+	if fallback == nil {
+		return nil, ErrNoPricingPlan
+	}
+	return fallback, nil
+}
+
+// scanPlan reads a row of planColumns
+func scanPlan(rows *sql.Rows) (*models.PricingPlan, error) {
+	var plan models.PricingPlan
+	err := rows.Scan(&plan.ID, &plan.ZoneID, &plan.VehicleClass, &plan.Currency, &plan.BaseFare, &plan.PerKm,
+		&plan.PerMinute, &plan.BookingFee, &plan.MinimumFare, &plan.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
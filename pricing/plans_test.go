@@ -0,0 +1,48 @@
+package pricing
+
+import (
+	"rider-assignment-system/models"
+	"testing"
+)
+
+func TestValidatePlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    models.PricingPlan
+		wantErr bool
+	}{
+		{"valid", models.PricingPlan{VehicleClass: "xl", Currency: "EUR", BaseFare: 2, PerKm: 1, PerMinute: 0.2, MinimumFare: 5}, false},
+		{"free", models.PricingPlan{Currency: "EUR"}, false},
+		{"no currency", models.PricingPlan{}, true},
+		{"long currency", models.PricingPlan{Currency: "EURO"}, true},
+		{"negative base fare", models.PricingPlan{Currency: "EUR", BaseFare: -1}, true},
+		{"negative per km", models.PricingPlan{Currency: "EUR", PerKm: -1}, true},
+		{"negative per minute", models.PricingPlan{Currency: "EUR", PerMinute: -1}, true},
+		{"negative booking fee", models.PricingPlan{Currency: "EUR", BookingFee: -1}, true},
+		{"negative minimum fare", models.PricingPlan{Currency: "EUR", MinimumFare: -1}, true},
+	}
+	for _, tt := range tests {
+		err := ValidatePlan(&tt.plan)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidatePlan() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidatePlanFillsDefaults(t *testing.T) {
+	plan := models.PricingPlan{Currency: "eur"}
+	if err := ValidatePlan(&plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan.VehicleClass != models.VehicleStandard || plan.Currency != "EUR" {
+		t.Errorf("ValidatePlan() left class %q and currency %q, want %q and EUR", plan.VehicleClass, plan.Currency, models.VehicleStandard)
+	}
+
+	plan = models.PricingPlan{VehicleClass: "xl", Currency: "usd"}
+	if err := ValidatePlan(&plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan.VehicleClass != "xl" {
+		t.Errorf("ValidatePlan() replaced class xl with %q", plan.VehicleClass)
+	}
+}
@@ -0,0 +1,197 @@
+package routing
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"rider-assignment-system/cache"
+	"rider-assignment-system/geohash"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const routeKeyPrefix = "route:"
+
+// CachedRouter answers repeated lookups from a cache in front of another router. Origins and
+// destinations are quantised to geohash cells, so lookups between the same pair of cells share
+// one entry. Entries live in an in-process LRU and in Redis (when connected), both for TTL;
+// the Redis tier lets instances share routes and keeps them across restarts.
+//
+// Failed lookups are not cached, so wrap the router that can fail, not a FallbackRouter.
+type CachedRouter struct {
+	Next      Router
+	TTL       time.Duration
+	Precision uint // geohash precision of the quantised points
+
+	local  *routeLRU
+	hits   uint64 // answered from the in-process tier
+	shared uint64 // answered from Redis
+	misses uint64 // answered by Next
+}
+
+// CacheStats counts how cached lookups were answered.
+type CacheStats struct {
+	LocalHits uint64 `json:"local_hits"`
+	RedisHits uint64 `json:"redis_hits"`
+	Misses    uint64 `json:"misses"`
+	Entries   int    `json:"local_entries"`
+}
+
+// NewCachedRouter caches next's routes for ttl, keeping up to size of them in process.
+func NewCachedRouter(next Router, ttl time.Duration, size int, precision uint) *CachedRouter {
+	return &CachedRouter{Next: next, TTL: ttl, Precision: precision, local: newRouteLRU(size)}
+}
+
+func (c *CachedRouter) Name() string { return c.Next.Name() }
+
+// Route returns the cached route between the cells of from and to, asking Next on a miss.
+func (c *CachedRouter) Route(ctx context.Context, from, to Point) (Route, error) {
+	key := c.key(from, to)
+	now := time.Now()
+	if route, ok := c.local.get(key, now); ok {
+		atomic.AddUint64(&c.hits, 1)
+		route.Cached = true
+		return route, nil
+	}
+	if route, ok := c.getShared(ctx, key); ok {
+		atomic.AddUint64(&c.shared, 1)
+		c.local.add(key, route, now.Add(c.TTL))
+		route.Cached = true
+		return route, nil
+	}
+
+	atomic.AddUint64(&c.misses, 1)
+	route, err := c.Next.Route(ctx, from, to)
+	if err != nil {
+		return Route{}, err
+	}
+	c.local.add(key, route, now.Add(c.TTL))
+	c.setShared(ctx, key, route)
+	return route, nil
+}
+
+// Matrix passes whole matrices through to Next uncached, so that ComputeMatrix falls back to
+// cached pairwise lookups when Next cannot compute them.
+func (c *CachedRouter) Matrix(ctx context.Context, sources, destinations []Point) (Matrix, error) {
+	next, ok := c.Next.(MatrixRouter)
+	if !ok {
+		return Matrix{}, fmt.Errorf("%s does not compute matrices", c.Next.Name())
+	}
+	return next.Matrix(ctx, sources, destinations)
+}
+
+// Stats returns the cache's counters since it was created.
+func (c *CachedRouter) Stats() CacheStats {
+	return CacheStats{
+		LocalHits: atomic.LoadUint64(&c.hits),
+		RedisHits: atomic.LoadUint64(&c.shared),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Entries:   c.local.len(),
+	}
+}
+
+// key names the entry of a pair of points, e.g. "route:osrm:u33dc0r:u33dbfz"
+func (c *CachedRouter) key(from, to Point) string {
+	return fmt.Sprintf("%s%s:%s:%s", routeKeyPrefix, c.Next.Name(),
+		geohash.Encode(from.Lat, from.Lon, c.Precision), geohash.Encode(to.Lat, to.Lon, c.Precision))
+}
+
+// getShared looks a route up in Redis. Redis being unavailable counts as a miss.
+func (c *CachedRouter) getShared(ctx context.Context, key string) (Route, bool) {
+	if cache.Rdb == nil {
+		return Route{}, false
+	}
+	data, err := cache.Rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Failed to read cached route %s: %v", key, err)
+		}
+		return Route{}, false
+	}
+	var route Route
+	if err := json.Unmarshal(data, &route); err != nil {
+		return Route{}, false
+	}
+	return route, true
+}
+
+// setShared stores a route in Redis for the TTL, logging failures
+func (c *CachedRouter) setShared(ctx context.Context, key string, route Route) {
+	if cache.Rdb == nil {
+		return
+	}
+	data, err := json.Marshal(route)
+	if err != nil {
+		return
+	}
+	if err := cache.Rdb.Set(ctx, key, data, c.TTL).Err(); err != nil {
+		log.Printf("Failed to cache route %s: %v", key, err)
+	}
+}
+
+// routeLRU is a fixed-size, least recently used map of routes with expiry times
+type routeLRU struct {
+	lock    sync.Mutex
+	size    int
+	order   *list.List // of *routeEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type routeEntry struct {
+	key     string
+	route   Route
+	expires time.Time
+}
+
+func newRouteLRU(size int) *routeLRU {
+	if size <= 0 {
+		size = 1
+	}
+	return &routeLRU{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (l *routeLRU) get(key string, now time.Time) (Route, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return Route{}, false
+	}
+	entry := element.Value.(*routeEntry)
+	if !now.Before(entry.expires) {
+		l.order.Remove(element)
+		delete(l.entries, key)
+		return Route{}, false
+	}
+	l.order.MoveToFront(element)
+	return entry.route, true
+}
+
+func (l *routeLRU) add(key string, route Route, expires time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if element, ok := l.entries[key]; ok {
+		element.Value = &routeEntry{key: key, route: route, expires: expires}
+		l.order.MoveToFront(element)
+		return
+	}
+	l.entries[key] = l.order.PushFront(&routeEntry{key: key, route: route, expires: expires})
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*routeEntry).key)
+	}
+}
+
+func (l *routeLRU) len() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.order.Len()
+}
@@ -0,0 +1,203 @@
+package routing
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"rider-assignment-system/cache"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// countingOSRM starts a stub OSRM server that answers every route request with the same route
+// and counts the requests it receives.
+func countingOSRM(t *testing.T) (*OSRMRouter, *int64) {
+	t.Helper()
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if !strings.HasPrefix(r.URL.Path, "/route/v1/driving/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"code":"Ok","routes":[{"distance":2500,"duration":300,"geometry":"_p~iF~ps|U_ulLnnqC"}]}`)
+	}))
+	t.Cleanup(server.Close)
+	return NewOSRMRouter(server.URL, time.Second), &requests
+}
+
+// fakeRedis starts a server speaking just enough of the Redis protocol for the route cache,
+// GET and SET, and points cache.Rdb at it for the rest of the test.
+func fakeRedis(t *testing.T) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	var lock sync.Mutex
+	values := make(map[string]string)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveRedis(conn, &lock, values)
+		}
+	}()
+
+	previous := cache.Rdb
+	cache.Rdb = redis.NewClient(&redis.Options{Addr: listener.Addr().String()})
+	t.Cleanup(func() {
+		cache.Rdb.Close()
+		cache.Rdb = previous
+		listener.Close()
+	})
+}
+
+// serveRedis answers the commands of one connection until it is closed.
+func serveRedis(conn net.Conn, lock *sync.Mutex, values map[string]string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readRedisCommand(reader)
+		if err != nil {
+			return
+		}
+		lock.Lock()
+		switch strings.ToUpper(args[0]) {
+		case "GET":
+			if value, ok := values[args[1]]; ok {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		case "SET":
+			values[args[1]] = args[2]
+			fmt.Fprint(conn, "+OK\r\n")
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		lock.Unlock()
+	}
+}
+
+// readRedisCommand reads one command, sent as an array of bulk strings.
+func readRedisCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestCachedRouterSharesLookupsWithinACellPair(t *testing.T) {
+	previous := cache.Rdb
+	cache.Rdb = nil // in process only
+	defer func() { cache.Rdb = previous }()
+
+	osrm, requests := countingOSRM(t)
+	router := NewCachedRouter(osrm, time.Minute, 100, 7)
+	ctx := context.Background()
+
+	// Points a few meters apart fall in the same precision 7 cells (~150 m)
+	for i := 0; i < 5; i++ {
+		offset := float64(i) * 0.00001
+		route, err := router.Route(ctx, Point{Lat: 52.52001 + offset, Lon: 13.40501}, Point{Lat: 52.53001, Lon: 13.42001 + offset})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if route.DistanceMeters != 2500 || route.Cached != (i > 0) {
+			t.Errorf("lookup %d: %+v", i, route)
+		}
+	}
+	if got := atomic.LoadInt64(requests); got != 1 {
+		t.Errorf("OSRM got %d requests, want 1", got)
+	}
+	if stats := router.Stats(); stats != (CacheStats{LocalHits: 4, Misses: 1, Entries: 1}) {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// A different cell pair is a new lookup
+	if _, err := router.Route(ctx, Point{Lat: 52.5, Lon: 13.3}, Point{Lat: 52.53001, Lon: 13.42001}); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt64(requests); got != 2 {
+		t.Errorf("OSRM got %d requests, want 2", got)
+	}
+}
+
+func TestCachedRouterFallsBackToRedisAfterEviction(t *testing.T) {
+	fakeRedis(t)
+	osrm, requests := countingOSRM(t)
+	router := NewCachedRouter(osrm, time.Minute, 1, 7) // room for one route in process
+	ctx := context.Background()
+
+	first := [2]Point{{Lat: 52.52, Lon: 13.405}, {Lat: 52.53, Lon: 13.42}}
+	second := [2]Point{{Lat: 48.85, Lon: 2.35}, {Lat: 48.86, Lon: 2.36}}
+	for _, pair := range [][2]Point{first, second, first, first} {
+		if _, err := router.Route(ctx, pair[0], pair[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// first misses, second misses and evicts it, first comes back from Redis, then from the LRU
+	if got := atomic.LoadInt64(requests); got != 2 {
+		t.Errorf("OSRM got %d requests, want 2", got)
+	}
+	if stats := router.Stats(); stats != (CacheStats{LocalHits: 1, RedisHits: 1, Misses: 2, Entries: 1}) {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// Another instance sharing the Redis tier never asks OSRM for either pair
+	other := NewCachedRouter(osrm, time.Minute, 10, 7)
+	for _, pair := range [][2]Point{first, second} {
+		route, err := other.Route(ctx, pair[0], pair[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !route.Cached {
+			t.Errorf("route %v was not cached", pair)
+		}
+	}
+	if got := atomic.LoadInt64(requests); got != 2 {
+		t.Errorf("OSRM got %d requests, want 2", got)
+	}
+	if stats := other.Stats(); stats != (CacheStats{RedisHits: 2, Entries: 2}) {
+		t.Errorf("second instance stats = %+v", stats)
+	}
+}
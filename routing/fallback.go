@@ -0,0 +1,35 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// FallbackRouter asks Primary and, when it fails, answers with Fallback instead. The route's
+// Provider tells which of the two answered.
+type FallbackRouter struct {
+	Primary  Router
+	Fallback Router
+}
+
+func (f *FallbackRouter) Name() string { return f.Primary.Name() }
+
+// Matrix asks Primary for the whole matrix. When it cannot answer, ComputeMatrix falls back to
+// pairwise lookups, each of which can fall back on its own.
+func (f *FallbackRouter) Matrix(ctx context.Context, sources, destinations []Point) (Matrix, error) {
+	primary, ok := f.Primary.(MatrixRouter)
+	if !ok {
+		return Matrix{}, fmt.Errorf("%s does not compute matrices", f.Primary.Name())
+	}
+	return primary.Matrix(ctx, sources, destinations)
+}
+
+func (f *FallbackRouter) Route(ctx context.Context, from, to Point) (Route, error) {
+	route, err := f.Primary.Route(ctx, from, to)
+	if err == nil {
+		return route, nil
+	}
+	log.Printf("Routing with %s after %s failed: %v", f.Fallback.Name(), f.Primary.Name(), err)
+	return f.Fallback.Route(ctx, from, to)
+}
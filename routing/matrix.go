@@ -0,0 +1,107 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// ProviderMixed is reported for a matrix whose entries came from more than one provider.
+const ProviderMixed = "mixed"
+
+// Matrix holds the routes from every source to every destination: entry [i][j] is from
+// source i to destination j, and nil when no route was found.
+type Matrix struct {
+	DistancesMeters  [][]*float64 `json:"distances_meters"`
+	DurationsSeconds [][]*float64 `json:"durations_seconds"`
+	Provider         string       `json:"provider"`
+}
+
+// MatrixRouter is a Router that can compute a whole matrix in one request.
+type MatrixRouter interface {
+	Router
+	Matrix(ctx context.Context, sources, destinations []Point) (Matrix, error)
+}
+
+// ComputeMatrix returns the matrix of routes from sources to destinations. It asks router for
+// the whole matrix when it is a MatrixRouter, and otherwise, or when that fails, looks the pairs
+// up one by one with at most concurrency lookups in flight.
+func ComputeMatrix(ctx context.Context, router Router, sources, destinations []Point, concurrency int) (Matrix, error) {
+	if matrixRouter, ok := router.(MatrixRouter); ok {
+		matrix, err := matrixRouter.Matrix(ctx, sources, destinations)
+		if err == nil {
+			return matrix, nil
+		}
+		log.Printf("Computing a %dx%d matrix pairwise: %v", len(sources), len(destinations), err)
+	}
+	return pairwiseMatrix(ctx, router, sources, destinations, concurrency)
+}
+
+// pairwiseMatrix fills a matrix with one Route call per pair. It fails only when every pair does.
+func pairwiseMatrix(ctx context.Context, router Router, sources, destinations []Point, concurrency int) (Matrix, error) {
+	matrix := newMatrix(len(sources), len(destinations))
+	if len(sources) == 0 || len(destinations) == 0 {
+		matrix.Provider = router.Name()
+		return matrix, nil
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		wg        sync.WaitGroup
+		lock      sync.Mutex
+		providers = make(map[string]bool)
+		lastErr   error
+		slots     = make(chan struct{}, concurrency)
+	)
+	for i, source := range sources {
+		for j, destination := range destinations {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(i, j int, source, destination Point) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				route, err := router.Route(ctx, source, destination)
+				lock.Lock()
+				defer lock.Unlock()
+				if err != nil {
+					lastErr = err
+					return
+				}
+				distance, duration := route.DistanceMeters, route.DurationSeconds
+				matrix.DistancesMeters[i][j] = &distance
+				matrix.DurationsSeconds[i][j] = &duration
+				providers[route.Provider] = true
+			}(i, j, source, destination)
+		}
+	}
+	wg.Wait()
+
+	switch len(providers) {
+	case 0:
+		return Matrix{}, fmt.Errorf("no route found between any pair: %v", lastErr)
+	case 1:
+		for provider := range providers {
+			matrix.Provider = provider
+		}
+	default:
+		matrix.Provider = ProviderMixed
+	}
+	return matrix, nil
+}
+
+// newMatrix returns a matrix of the given size with every entry nil
+func newMatrix(sources, destinations int) Matrix {
+	matrix := Matrix{
+		DistancesMeters:  make([][]*float64, sources),
+		DurationsSeconds: make([][]*float64, sources),
+	}
+	for i := 0; i < sources; i++ {
+		matrix.DistancesMeters[i] = make([]*float64, destinations)
+		matrix.DurationsSeconds[i] = make([]*float64, destinations)
+	}
+	return matrix
+}
@@ -0,0 +1,45 @@
+package routing
+
+import (
+	"context"
+	"rider-assignment-system/geohash"
+)
+
+const (
+	// DefaultDetourFactor is how much longer than the straight line a road route is assumed to be.
+	DefaultDetourFactor = 1.3
+	// DefaultAverageSpeedKmh is the driving speed assumed for offline durations.
+	DefaultAverageSpeedKmh = 30.0
+)
+
+// OfflineRouter estimates routes without a routing service: the great-circle distance
+// stretched by a detour factor, driven at an average speed. It never fails, which makes it a
+// stand-in for tests and simulations and a fallback when the routing service is unreachable.
+type OfflineRouter struct {
+	DetourFactor    float64
+	AverageSpeedKmh float64
+}
+
+// NewOfflineRouter returns an offline router, using the defaults for non-positive values.
+func NewOfflineRouter(detourFactor, averageSpeedKmh float64) *OfflineRouter {
+	if detourFactor <= 0 {
+		detourFactor = DefaultDetourFactor
+	}
+	if averageSpeedKmh <= 0 {
+		averageSpeedKmh = DefaultAverageSpeedKmh
+	}
+	return &OfflineRouter{DetourFactor: detourFactor, AverageSpeedKmh: averageSpeedKmh}
+}
+
+func (o *OfflineRouter) Name() string { return ProviderOffline }
+
+// Route estimates the route between two points; its polyline is the straight line.
+func (o *OfflineRouter) Route(ctx context.Context, from, to Point) (Route, error) {
+	distanceKm := geohash.Haversine(from.Lat, from.Lon, to.Lat, to.Lon) * o.DetourFactor
+	return Route{
+		DistanceMeters:  distanceKm * 1000,
+		DurationSeconds: distanceKm / o.AverageSpeedKmh * 3600,
+		Polyline:        EncodePolyline([]Point{from, to}),
+		Provider:        ProviderOffline,
+	}, nil
+}
@@ -0,0 +1,168 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OSRMRouter routes with the HTTP API of an OSRM server.
+type OSRMRouter struct {
+	BaseURL string        // e.g. http://router.project-osrm.org
+	Profile string        // routing profile, "driving" when empty
+	Timeout time.Duration // per request, on top of any deadline of the caller's context
+	Client  *http.Client
+}
+
+// NewOSRMRouter returns a router for the OSRM server at baseURL.
+func NewOSRMRouter(baseURL string, timeout time.Duration) *OSRMRouter {
+	return &OSRMRouter{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Profile: "driving",
+		Timeout: timeout,
+		Client:  &http.Client{},
+	}
+}
+
+func (o *OSRMRouter) Name() string { return ProviderOSRM }
+
+// osrmRouteResponse is the subset of the OSRM route service response used here.
+type osrmRouteResponse struct {
+	Routes []osrmRoute `json:"routes"`
+}
+
+type osrmRoute struct {
+	Distance float64 `json:"distance"` // meters
+	Duration float64 `json:"duration"` // seconds
+	Geometry string  `json:"geometry"` // encoded polyline
+}
+
+// Route calls the route service for the fastest route between two points.
+func (o *OSRMRouter) Route(ctx context.Context, from, to Point) (Route, error) {
+	url := fmt.Sprintf("%s/route/v1/%s/%s?overview=simplified&geometries=polyline",
+		o.BaseURL, o.profile(), coordinates([]Point{from, to}))
+
+	var result osrmRouteResponse
+	if err := o.get(ctx, url, &result); err != nil {
+		return Route{}, err
+	}
+	if len(result.Routes) == 0 {
+		return Route{}, fmt.Errorf("no routes found in response")
+	}
+
+	route := result.Routes[0]
+	return Route{
+		DistanceMeters:  route.Distance,
+		DurationSeconds: route.Duration,
+		Polyline:        route.Geometry,
+		Provider:        ProviderOSRM,
+	}, nil
+}
+
+// osrmTableResponse is the subset of the OSRM table service response used here. Pairs without
+// a route are null.
+type osrmTableResponse struct {
+	Distances [][]*float64 `json:"distances"` // meters
+	Durations [][]*float64 `json:"durations"` // seconds
+}
+
+// Matrix calls the table service for the routes from every source to every destination.
+func (o *OSRMRouter) Matrix(ctx context.Context, sources, destinations []Point) (Matrix, error) {
+	if len(sources) == 0 || len(destinations) == 0 {
+		matrix := newMatrix(len(sources), len(destinations))
+		matrix.Provider = ProviderOSRM
+		return matrix, nil
+	}
+
+	url := fmt.Sprintf("%s/table/v1/%s/%s?sources=%s&destinations=%s&annotations=duration,distance",
+		o.BaseURL, o.profile(), coordinates(append(append([]Point{}, sources...), destinations...)),
+		indexRange(0, len(sources)), indexRange(len(sources), len(destinations)))
+
+	var result osrmTableResponse
+	if err := o.get(ctx, url, &result); err != nil {
+		return Matrix{}, err
+	}
+	if len(result.Distances) != len(sources) || len(result.Durations) != len(sources) {
+		return Matrix{}, fmt.Errorf("table response has %d rows, expected %d", len(result.Durations), len(sources))
+	}
+	for i := range sources {
+		if len(result.Distances[i]) != len(destinations) || len(result.Durations[i]) != len(destinations) {
+			return Matrix{}, fmt.Errorf("table response row %d has %d columns, expected %d", i, len(result.Durations[i]), len(destinations))
+		}
+	}
+
+	return Matrix{
+		DistancesMeters:  result.Distances,
+		DurationsSeconds: result.Durations,
+		Provider:         ProviderOSRM,
+	}, nil
+}
+
+// get fetches an OSRM service URL and decodes its response into result, failing unless the
+// service answered with code "Ok".
+func (o *OSRMRouter) get(ctx context.Context, url string, result interface{}) error {
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build routing request: %v", err)
+	}
+	response, err := o.Client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to reach routing service: %v", err)
+	}
+	defer response.Body.Close()
+
+	var status struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	decoder := json.NewDecoder(response.Body)
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return fmt.Errorf("failed to parse routing response (HTTP %d): %v", response.StatusCode, err)
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return fmt.Errorf("failed to parse routing response: %v", err)
+	}
+	if response.StatusCode != http.StatusOK || status.Code != "Ok" {
+		return fmt.Errorf("routing service returned %d %s: %s", response.StatusCode, status.Code, status.Message)
+	}
+	if err := json.Unmarshal(raw, result); err != nil {
+		return fmt.Errorf("failed to parse routing response: %v", err)
+	}
+	return nil
+}
+
+func (o *OSRMRouter) profile() string {
+	if o.Profile == "" {
+		return "driving"
+	}
+	return o.Profile
+}
+
+// indexRange formats count consecutive coordinate indexes from start, e.g. "2;3;4"
+func indexRange(start, count int) string {
+	indexes := make([]string, count)
+	for i := range indexes {
+		indexes[i] = strconv.Itoa(start + i)
+	}
+	return strings.Join(indexes, ";")
+}
+
+// coordinates formats points as OSRM expects them: "lon,lat" pairs separated by semicolons
+func coordinates(points []Point) string {
+	parts := make([]string, len(points))
+	for i, p := range points {
+		parts[i] = fmt.Sprintf("%f,%f", p.Lon, p.Lat)
+	}
+	return strings.Join(parts, ";")
+}
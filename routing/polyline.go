@@ -0,0 +1,34 @@
+package routing
+
+import (
+	"math"
+	"strings"
+)
+
+// EncodePolyline encodes points in the encoded polyline format with precision 5, as OSRM and
+// Google Maps return route geometries.
+func EncodePolyline(points []Point) string {
+	var b strings.Builder
+	var prevLat, prevLon int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Lat * 1e5))
+		lon := int64(math.Round(p.Lon * 1e5))
+		encodePolylineValue(&b, lat-prevLat)
+		encodePolylineValue(&b, lon-prevLon)
+		prevLat, prevLon = lat, lon
+	}
+	return b.String()
+}
+
+// encodePolylineValue appends one signed delta in 5-bit chunks
+func encodePolylineValue(b *strings.Builder, value int64) {
+	v := value << 1
+	if value < 0 {
+		v = ^v
+	}
+	for v >= 0x20 {
+		b.WriteByte(byte((0x20 | (v & 0x1f)) + 63))
+		v >>= 5
+	}
+	b.WriteByte(byte(v + 63))
+}
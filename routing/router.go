@@ -0,0 +1,110 @@
+package routing
+
+import (
+	"context"
+	"rider-assignment-system/config"
+	"sync"
+	"time"
+)
+
+const (
+	// ProviderOSRM names routes from an OSRM server.
+	ProviderOSRM = "osrm"
+	// ProviderOffline names routes estimated locally without a routing service.
+	ProviderOffline = "offline"
+)
+
+// Point is a location given as latitude and longitude in degrees.
+type Point struct {
+	Lat float64 `json:"latitude"`
+	Lon float64 `json:"longitude"`
+}
+
+// Route is a driving route between two points.
+type Route struct {
+	DistanceMeters  float64 `json:"distance_meters"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Polyline        string  `json:"polyline,omitempty"` // encoded polyline (precision 5) of the route geometry
+	Provider        string  `json:"provider"`           // name of the router that answered
+	Cached          bool    `json:"cached,omitempty"`   // served from the route cache
+}
+
+// DistanceKm returns the route distance in kilometers.
+func (r Route) DistanceKm() float64 {
+	return r.DistanceMeters / 1000
+}
+
+// Router computes driving routes. Implementations must be safe for concurrent use.
+type Router interface {
+	// Name identifies the provider, as reported in Route.Provider.
+	Name() string
+	// Route returns the driving route from one point to another.
+	Route(ctx context.Context, from, to Point) (Route, error)
+}
+
+var (
+	defaultRouter Router
+	defaultLock   sync.Mutex
+)
+
+// Default returns the router configured under routing.* in config, building it on first use.
+func Default() Router {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+
+	if defaultRouter == nil {
+		defaultRouter = NewFromConfig()
+	}
+	return defaultRouter
+}
+
+// SetDefault replaces the router returned by Default, for example with an OfflineRouter in
+// simulations.
+func SetDefault(router Router) {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+
+	defaultRouter = router
+}
+
+// NewFromConfig builds the router selected by routing.provider. OSRM routes are cached unless
+// routing.cache.enabled is false, and fall back to offline estimates when the server cannot
+// answer unless routing.fallback is false.
+func NewFromConfig() Router {
+	offline := NewOfflineRouter(
+		config.GetFloat("routing.detour_factor", DefaultDetourFactor),
+		config.GetFloat("routing.average_speed_kmh", DefaultAverageSpeedKmh),
+	)
+	if config.GetEnv("routing.provider", ProviderOSRM) == ProviderOffline {
+		return offline
+	}
+
+	var osrm Router = NewOSRMRouter(
+		config.GetEnv("routing.osrm_url", "http://router.project-osrm.org"),
+		config.GetDuration("routing.timeout", 2*time.Second),
+	)
+	if config.GetEnv("routing.cache.enabled", "true") != "false" {
+		osrm = NewCachedRouter(osrm,
+			config.GetDuration("routing.cache.ttl", 10*time.Minute),
+			config.GetInt("routing.cache.size", 10000),
+			uint(config.GetInt("routing.cache.precision", 7)),
+		)
+	}
+	if config.GetEnv("routing.fallback", "true") == "false" {
+		return osrm
+	}
+	return &FallbackRouter{Primary: osrm, Fallback: offline}
+}
+
+// DefaultCacheStats returns the counters of the default router's route cache, and false when
+// it has none.
+func DefaultCacheStats() (CacheStats, bool) {
+	router := Default()
+	if fallback, ok := router.(*FallbackRouter); ok {
+		router = fallback.Primary
+	}
+	if cached, ok := router.(*CachedRouter); ok {
+		return cached.Stats(), true
+	}
+	return CacheStats{}, false
+}
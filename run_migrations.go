@@ -1,13 +1,25 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"rider-assignment-system/config"
 	"rider-assignment-system/migration"
 )
 
 func main() {
+	rehash := flag.Bool("rehash-geohash", false, "re-encode driver geohashes at the configured precision")
+	flag.Parse()
+
 	// Run the migrations
 	if err := migration.RunMigrations(); err != nil {
 		log.Fatalf("Migration error: %v", err)
 	}
+
+	if *rehash {
+		config.InitConfig()
+		if err := migration.RehashDrivers(); err != nil {
+			log.Fatalf("Rehash error: %v", err)
+		}
+	}
 }
@@ -0,0 +1,179 @@
+package surge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"rider-assignment-system/cache"
+	"rider-assignment-system/config"
+	"rider-assignment-system/geohash"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Compute recalculates the surge of every cell and publishes it for MultiplierAt and Heatmap.
+//
+// Demand is the ride requests recorded in each cell over surge.window, and supply the
+// available drivers in it. Both are smoothed spatially by adding surge.neighbor_weight of the
+// eight neighbouring cells, so a busy cell next to idle drivers surges less and surge fades out
+// at the edges of a hotspot. The ratio of the two maps to a multiplier of 1 up to
+// surge.threshold, rising by surge.sensitivity per unit of ratio beyond it, capped at
+// surge.max_multiplier. Multipliers are then smoothed over time, moving only surge.smoothing of
+// the way from the previous multiplier towards the new one each run.
+func Compute(ctx context.Context, now time.Time) ([]CellSurge, error) {
+	precision := Precision()
+	demand, err := demandByCell(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	supply, err := cache.CountDriversByCell(ctx, precision)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := loadCells(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cells := surgeCells(demand, supply, previous)
+	if err := publish(ctx, cells, now); err != nil {
+		return nil, err
+	}
+	return cells, nil
+}
+
+// surgeCells computes the surge of every cell from the demand and supply counted in each and the
+// multipliers of the previous run, leaving out cells that are calm
+func surgeCells(demand map[string]float64, supply map[string]int64, previous map[string]CellSurge) []CellSurge {
+	// Cells with demand, the neighbours it spills into, and cells still cooling down
+	candidates := make(map[string]bool)
+	for cell := range demand {
+		candidates[cell] = true
+		for _, neighbor := range geohash.GetNeighbors(cell) {
+			candidates[neighbor] = true
+		}
+	}
+	for cell := range previous {
+		candidates[cell] = true
+	}
+
+	neighborWeight := config.GetFloat("surge.neighbor_weight", 0.5)
+	smoothing := config.GetFloat("surge.smoothing", 0.5)
+	var cells []CellSurge
+	for cell := range candidates {
+		d, s := demand[cell], float64(supply[cell])
+		for _, neighbor := range geohash.GetNeighbors(cell) {
+			d += neighborWeight * demand[neighbor]
+			s += neighborWeight * float64(supply[neighbor])
+		}
+		ratio := d / math.Max(s, 1)
+
+		last := 1.0
+		if prev, ok := previous[cell]; ok {
+			last = prev.Multiplier
+		}
+		multiplier := smoothing*multiplierFor(ratio) + (1-smoothing)*last
+		if multiplier < 1.01 {
+			continue // calm again
+		}
+		multiplier = math.Round(multiplier*100) / 100
+
+		lat, lon := geohash.Decode(cell)
+		cells = append(cells, CellSurge{Cell: cell, Lat: lat, Lon: lon, Demand: d, Supply: s, Ratio: ratio, Multiplier: multiplier})
+	}
+	return cells
+}
+
+// multiplierFor maps a demand/supply ratio to a surge multiplier
+func multiplierFor(ratio float64) float64 {
+	threshold := config.GetFloat("surge.threshold", 1)
+	if ratio <= threshold {
+		return 1
+	}
+	multiplier := 1 + config.GetFloat("surge.sensitivity", 0.5)*(ratio-threshold)
+	return math.Min(multiplier, config.GetFloat("surge.max_multiplier", 3))
+}
+
+// demandByCell sums the ride requests per cell over the window ending at now
+func demandByCell(ctx context.Context, now time.Time) (map[string]float64, error) {
+	keys := demandKeys(now)
+	pipe := cache.Rdb.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.HGetAll(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load surge demand: %v", err)
+	}
+
+	counts := make([]map[string]string, len(cmds))
+	for i, cmd := range cmds {
+		counts[i] = cmd.Val()
+	}
+	return sumDemand(counts), nil
+}
+
+// demandKeys names the demand counters of every minute in the window ending at now
+func demandKeys(now time.Time) []string {
+	keys := make([]string, int(math.Ceil(window().Minutes())))
+	for i := range keys {
+		keys[i] = demandKey(now.Add(-time.Duration(i) * time.Minute))
+	}
+	return keys
+}
+
+// sumDemand adds up per-minute demand counters by cell, ignoring counts that are not numbers
+func sumDemand(counts []map[string]string) map[string]float64 {
+	demand := make(map[string]float64)
+	for _, minute := range counts {
+		for cell, count := range minute {
+			if n, err := strconv.ParseFloat(count, 64); err == nil {
+				demand[cell] += n
+			}
+		}
+	}
+	return demand
+}
+
+// publish replaces the surging cells in Redis
+func publish(ctx context.Context, cells []CellSurge, now time.Time) error {
+	fields := make([]interface{}, 0, 2*len(cells))
+	for _, cell := range cells {
+		data, err := json.Marshal(cell)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, cell.Cell, data)
+	}
+
+	pipe := cache.Rdb.TxPipeline()
+	pipe.Del(ctx, cellsKey)
+	if len(fields) > 0 {
+		pipe.HSet(ctx, cellsKey, fields...)
+	}
+	pipe.Set(ctx, updatedKey, now.UTC().Format(time.RFC3339), 0)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to publish surge: %v", err)
+	}
+	return nil
+}
+
+// StartEngine periodically recomputes surge while surge.enabled is set.
+func StartEngine(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !Enabled() {
+				continue
+			}
+			if _, err := Compute(context.Background(), time.Now()); err != nil {
+				log.Printf("Surge computation failed: %v", err)
+			}
+		}
+	}()
+}
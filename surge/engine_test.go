@@ -0,0 +1,143 @@
+package surge
+
+import (
+	"reflect"
+	"rider-assignment-system/geohash"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestMultiplierFor(t *testing.T) {
+	defer viper.Reset()
+	tests := []struct {
+		name                        string
+		threshold, sensitivity, cap float64
+		ratio                       float64
+		want                        float64
+	}{
+		{"below the threshold", 1, 0.5, 3, 0.5, 1},
+		{"at the threshold", 1, 0.5, 3, 1, 1},
+		{"beyond the threshold", 1, 0.5, 3, 3, 2},
+		{"capped", 1, 0.5, 3, 10, 3},
+		{"higher threshold", 2, 0.5, 3, 3, 1.5},
+		{"higher sensitivity", 1, 1, 3, 2.5, 2.5},
+		{"lower cap", 1, 0.5, 1.5, 3, 1.5},
+	}
+	for _, tt := range tests {
+		viper.Set("surge.threshold", tt.threshold)
+		viper.Set("surge.sensitivity", tt.sensitivity)
+		viper.Set("surge.max_multiplier", tt.cap)
+		if got := multiplierFor(tt.ratio); got != tt.want {
+			t.Errorf("%s: multiplierFor(%v) = %v, want %v", tt.name, tt.ratio, got, tt.want)
+		}
+	}
+}
+
+// multipliers indexes the multiplier of each surging cell
+func multipliers(cells []CellSurge) map[string]float64 {
+	byCell := make(map[string]float64, len(cells))
+	for _, cell := range cells {
+		byCell[cell.Cell] = cell.Multiplier
+	}
+	return byCell
+}
+
+func TestSurgeCellsSmoothsDemandAndSupplyOverNeighbours(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("surge.threshold", 1)
+	viper.Set("surge.sensitivity", 0.5)
+	viper.Set("surge.max_multiplier", 3)
+	viper.Set("surge.smoothing", 1) // no temporal smoothing
+	center := "u33db"
+	neighbors := geohash.GetNeighbors(center)
+
+	// Demand of 4 with no drivers: the centre has a ratio of 4 and each neighbour half of it
+	viper.Set("surge.neighbor_weight", 0.5)
+	got := multipliers(surgeCells(map[string]float64{center: 4}, nil, nil))
+	want := map[string]float64{center: 2.5}
+	for _, neighbor := range neighbors {
+		want[neighbor] = 1.5
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("spread demand: got %v, want %v", got, want)
+	}
+
+	// Drivers next door count half towards the centre's supply: 4 / (0.5 * 4) = 2
+	got = multipliers(surgeCells(map[string]float64{center: 4}, map[string]int64{neighbors[0]: 4}, nil))
+	if got[center] != 1.5 {
+		t.Errorf("centre next to drivers surged %v, want 1.5", got[center])
+	}
+
+	// Without neighbour weight demand stays in its cell
+	viper.Set("surge.neighbor_weight", 0)
+	got = multipliers(surgeCells(map[string]float64{center: 4}, map[string]int64{neighbors[0]: 4}, nil))
+	if !reflect.DeepEqual(got, map[string]float64{center: 2.5}) {
+		t.Errorf("unweighted: got %v, want only %s at 2.5", got, center)
+	}
+}
+
+func TestSurgeCellsSmoothsOverTime(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("surge.threshold", 1)
+	viper.Set("surge.sensitivity", 0.5)
+	viper.Set("surge.max_multiplier", 3)
+	viper.Set("surge.neighbor_weight", 0)
+	viper.Set("surge.smoothing", 0.5)
+
+	previous := map[string]CellSurge{
+		"u33db": {Cell: "u33db", Multiplier: 3},    // still busy: halfway from 3 to 2.5
+		"u33dc": {Cell: "u33dc", Multiplier: 2},    // demand gone: halfway back to 1
+		"u33df": {Cell: "u33df", Multiplier: 1.01}, // calm again once halfway to 1
+	}
+	got := multipliers(surgeCells(map[string]float64{"u33db": 4, "u33dg": 4}, nil, previous))
+	want := map[string]float64{"u33db": 2.75, "u33dc": 1.5, "u33dg": 1.75}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	viper.Set("surge.smoothing", 1)
+	got = multipliers(surgeCells(map[string]float64{"u33db": 4}, nil, previous))
+	if !reflect.DeepEqual(got, map[string]float64{"u33db": 2.5}) {
+		t.Errorf("unsmoothed: got %v, want only u33db at 2.5", got)
+	}
+}
+
+func TestDemandKeysCoverTheWindow(t *testing.T) {
+	defer viper.Reset()
+	now := time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC)
+
+	viper.Set("surge.window", "10m")
+	keys := demandKeys(now)
+	if len(keys) != 10 {
+		t.Fatalf("%d keys for a 10 minute window, want 10", len(keys))
+	}
+	if keys[0] != demandKey(now) || keys[9] != demandKey(now.Add(-9*time.Minute)) {
+		t.Errorf("keys run from %s to %s, want the minutes from 12:30 back to 12:21", keys[0], keys[9])
+	}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if seen[key] {
+			t.Errorf("minute %s read twice", key)
+		}
+		seen[key] = true
+	}
+
+	viper.Set("surge.window", "90s")
+	if keys := demandKeys(now); len(keys) != 2 {
+		t.Errorf("%d keys for a 90 second window, want 2", len(keys))
+	}
+}
+
+func TestSumDemand(t *testing.T) {
+	got := sumDemand([]map[string]string{
+		{"u33db": "2", "u33dc": "oops"},
+		{},
+		{"u33db": "3", "u33df": "1"},
+	})
+	want := map[string]float64{"u33db": 5, "u33df": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sumDemand() = %v, want %v", got, want)
+	}
+}
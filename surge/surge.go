@@ -0,0 +1,154 @@
+package surge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"rider-assignment-system/cache"
+	"rider-assignment-system/config"
+	"rider-assignment-system/geohash"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	demandKeyPrefix = "surge:demand:"     // + Unix minute: hash of geohash cell to ride requests
+	cellsKey        = "surge:cells"       // hash of geohash cell to its JSON-encoded CellSurge
+	updatedKey      = "surge:computed_at" // when cellsKey was last computed
+)
+
+// CellSurge is the surge state of a geohash cell.
+type CellSurge struct {
+	Cell       string  `json:"cell"`
+	Lat        float64 `json:"latitude"` // centre of the cell
+	Lon        float64 `json:"longitude"`
+	Demand     float64 `json:"demand"` // ride requests in the window, including a share of the neighbours'
+	Supply     float64 `json:"supply"` // available drivers, including a share of the neighbours'
+	Ratio      float64 `json:"ratio"`  // demand over supply
+	Multiplier float64 `json:"multiplier"`
+}
+
+// Enabled reports whether fares are surged.
+func Enabled() bool {
+	return config.GetEnv("surge.enabled", "true") != "false"
+}
+
+// Precision returns the geohash precision of surge cells: surge.precision, or the closest
+// precision the driver cells are indexed at, since supply is counted from them. With no index
+// precisions configured it is surge.precision, kept within 1 to 12.
+func Precision() uint {
+	want := config.GetInt("surge.precision", 6)
+	precisions := geohash.IndexPrecisions()
+	if len(precisions) == 0 {
+		return uint(min(max(want, 1), 12))
+	}
+	best := precisions[0]
+	for _, p := range precisions {
+		if abs(int(p)-want) < abs(int(best)-want) {
+			best = p
+		}
+	}
+	return best
+}
+
+// RecordDemand counts a ride request at its pickup towards the surge of its cell.
+func RecordDemand(ctx context.Context, lat, lon float64) {
+	if cache.Rdb == nil || !Enabled() {
+		return
+	}
+	key := demandKey(time.Now())
+	cell := geohash.Encode(lat, lon, Precision())
+	// Not retried: a request counted twice would skew demand more than one missed
+	pipe := cache.Rdb.TxPipeline()
+	pipe.HIncrBy(ctx, key, cell, 1)
+	pipe.Expire(ctx, key, window()+2*time.Minute)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to record surge demand: %v", err)
+	}
+}
+
+// MultiplierAt returns the surge of the cell containing a location. Cells without surge, and
+// every cell when surge is disabled or Redis cannot be read, have a multiplier of 1.
+func MultiplierAt(ctx context.Context, lat, lon float64) CellSurge {
+	cell := geohash.Encode(lat, lon, Precision())
+	centerLat, centerLon := geohash.Decode(cell)
+	calm := CellSurge{Cell: cell, Lat: centerLat, Lon: centerLon, Multiplier: 1}
+	if cache.Rdb == nil || !Enabled() {
+		return calm
+	}
+
+	data, err := cache.Rdb.HGet(ctx, cellsKey, cell).Bytes()
+	if err != nil {
+		return calm
+	}
+	var surge CellSurge
+	if err := json.Unmarshal(data, &surge); err != nil {
+		return calm
+	}
+	return surge
+}
+
+// Heatmap returns every surging cell, as last computed, highest multiplier first, and when it
+// was computed.
+func Heatmap(ctx context.Context) ([]CellSurge, time.Time, error) {
+	if cache.Rdb == nil {
+		return nil, time.Time{}, fmt.Errorf("redis is not connected")
+	}
+	surges, err := loadCells(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	cells := make([]CellSurge, 0, len(surges))
+	for _, surge := range surges {
+		cells = append(cells, surge)
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Multiplier != cells[j].Multiplier {
+			return cells[i].Multiplier > cells[j].Multiplier
+		}
+		return cells[i].Cell < cells[j].Cell
+	})
+
+	var computedAt time.Time
+	if value, err := cache.Rdb.Get(ctx, updatedKey).Result(); err == nil {
+		computedAt, _ = time.Parse(time.RFC3339, value)
+	}
+	return cells, computedAt, nil
+}
+
+// loadCells reads the last computed surge of every cell
+func loadCells(ctx context.Context) (map[string]CellSurge, error) {
+	values, err := cache.Rdb.HGetAll(ctx, cellsKey).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to load surge cells: %v", err)
+	}
+	cells := make(map[string]CellSurge, len(values))
+	for cell, value := range values {
+		var surge CellSurge
+		if json.Unmarshal([]byte(value), &surge) == nil {
+			cells[cell] = surge
+		}
+	}
+	return cells, nil
+}
+
+// demandKey names the demand counters of the minute containing t
+func demandKey(t time.Time) string {
+	return demandKeyPrefix + strconv.FormatInt(t.Unix()/60, 10)
+}
+
+// window is how far back ride requests count towards demand
+func window() time.Duration {
+	return config.GetDuration("surge.window", 10*time.Minute)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,29 @@
+package surge
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestPrecision(t *testing.T) {
+	defer viper.Reset()
+	tests := []struct {
+		name       string
+		precisions []int
+		surge      int
+		want       uint
+	}{
+		{"an indexed precision", []int{7, 6, 5}, 6, 6},
+		{"the closest indexed precision", []int{7, 5}, 4, 5},
+		{"no valid index precisions", []int{0}, 6, 6},
+		{"no valid index precisions, clamped", []int{-1}, 20, 12},
+	}
+	for _, tt := range tests {
+		viper.Set("geohash.index_precisions", tt.precisions)
+		viper.Set("surge.precision", tt.surge)
+		if got := Precision(); got != tt.want {
+			t.Errorf("%s: Precision() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}